@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exitAssertionFailed is the exit status when the shape does not meet the
+// --assert-* expectations, distinct from 1 for errors and 2 for bad usage.
+const exitAssertionFailed = 3
+
+// assertions are the expectations about the inferred shape set with the
+// --assert-* flags, for scripts that gate on a data drop.
+type assertions struct {
+	required       []string // paths of fields every object must hold
+	noUnknownTypes bool     // no field may be only null or empty arrays
+}
+
+// parseAssertions parses the comma-separated paths of --assert-required,
+// written as find prints them: "user.id", "items[].id". As in filter
+// patterns, "[]" may be left out.
+func parseAssertions(required string, noUnknownTypes bool) (*assertions, error) {
+	a := &assertions{noUnknownTypes: noUnknownTypes}
+	for _, path := range strings.Split(required, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		path, err := canonicalPath(path)
+		if err == nil {
+			_, err = keyPath(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("--assert-required: %v", err)
+		}
+		a.required = append(a.required, path)
+	}
+	return a, nil
+}
+
+// assertionFailure is an unmet expectation: the check, the path of the
+// field and what was found instead.
+type assertionFailure struct {
+	check  string
+	path   string
+	reason string
+}
+
+// String formats the failure as one tab-separated line for scripts.
+func (f assertionFailure) String() string {
+	return f.check + "\t" + f.path + "\t" + f.reason
+}
+
+// check returns the failed expectations, required fields first in the order
+// given, then fields of unknown type in output order.
+func (a *assertions) check(fields map[string]*FieldInfo) []assertionFailure {
+	all := findFields(fields, func(string) bool { return true })
+	byKeys := make(map[string]fieldMatch, len(all))
+	for _, m := range all {
+		keys, _ := keyPath(m.path)
+		byKeys[keys] = m
+	}
+
+	var failures []assertionFailure
+	for _, path := range a.required {
+		keys, _ := keyPath(path)
+		m, ok := byKeys[keys]
+		switch {
+		case !ok:
+			failures = append(failures, assertionFailure{"required", path, "missing"})
+		case m.field.Optional && m.field.Assumed:
+			failures = append(failures, assertionFailure{"required", path, "presence unknown, seen in a single object"})
+		case m.field.Optional:
+			failures = append(failures, assertionFailure{"required", path, fmt.Sprintf("present in %d of %d", m.present, m.objects)})
+		}
+	}
+	if a.noUnknownTypes {
+		for _, m := range all {
+			if strings.Contains(m.field.Type, "unknown") {
+				failures = append(failures, assertionFailure{"no-unknown-types", m.path, "type " + m.field.Type})
+			}
+		}
+	}
+	return failures
+}
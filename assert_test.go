@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssertions(t *testing.T) {
+	fields := analyzeJSON([]interface{}{
+		map[string]interface{}{"id": 1.0, "items": []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"sku": "a"}}, "note": nil},
+		map[string]interface{}{"id": 2.0, "tags": []interface{}{}},
+	})
+
+	tests := []struct {
+		name           string
+		required       string
+		noUnknownTypes bool
+		want           []string
+	}{
+		{"none", "", false, nil},
+		{"required met", " id,", false, nil},
+		{"required unmet", "items,items[].id,user.id", false, []string{
+			"required\titems\tpresent in 1 of 2",
+			"required\titems[].id\tpresent in 1 of 2",
+			"required\tuser.id\tmissing",
+		}},
+		{"[] left out", "items.id,items[].sku", false, []string{
+			"required\titems.id\tpresent in 1 of 2",
+			"required\titems[].sku\tpresent in 1 of 2",
+		}},
+		{"unknown types", "", true, []string{
+			"no-unknown-types\tnote\ttype unknown",
+			"no-unknown-types\ttags\ttype array<unknown>",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			a, err := parseAssertions(tt.required, tt.noUnknownTypes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range a.check(fields) {
+				got = append(got, f.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAssertionsIndex(t *testing.T) {
+	if _, err := parseAssertions("items[0].id", false); err == nil {
+		t.Error("expected an error for an element index")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type avroRecord struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name"`
+	Fields []avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    interface{}     `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type avroArray struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+func writeAvro(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	records := map[string]bool{"root": true}
+	out, err := json.MarshalIndent(avroRecordFor(fields, "root", "", records, opts), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// avroRecordFor builds a record for fields. Avro record names must be unique
+// within a schema, so nested records are named after their path from the
+// root, and numbered when paths join into a name records already holds:
+// a.b_c and a_b.c are both root_a_b_c.
+func avroRecordFor(fields map[string]*FieldInfo, name, parent string, records map[string]bool, opts *renderOptions) avroRecord {
+	keys := sortedKeys(fields)
+
+	record := avroRecord{Type: "record", Name: name, Fields: []avroField{}}
+	names := uniqueNames(keys, avroName)
+	for _, key := range keys {
+		field := fields[key]
+		path := fieldPath(parent, key)
+		fieldName := names[key]
+		if fieldName != key {
+			opts.warn(path, fmt.Sprintf("not a valid Avro name; renamed to %q", fieldName))
+		}
+
+		var typ interface{}
+		if field.Type == "" {
+			recordName := name + "_" + fieldName
+			for n := 2; records[recordName]; n++ {
+				recordName = fmt.Sprintf("%s_%s_%d", name, fieldName, n)
+			}
+			records[recordName] = true
+			typ = avroRecordFor(field.Children, recordName, childPath(path, field), records, opts)
+			if field.isArray {
+				typ = avroArray{Type: "array", Items: typ}
+			}
+		} else {
+			typ = avroLeafType(field.Type, path, opts)
+		}
+
+		f := avroField{Name: fieldName, Type: typ}
+		if field.Optional || field.Nullable {
+			// Optional and nullable fields become a nullable union
+			// defaulting to null, which requires "null" to be the first
+			// branch.
+			if typ != "null" {
+				f.Type = []interface{}{"null", typ}
+			}
+			f.Default = json.RawMessage("null")
+		}
+		record.Fields = append(record.Fields, f)
+	}
+	return record
+}
+
+func avroLeafType(typ, path string, opts *renderOptions) interface{} {
+	if strings.HasPrefix(typ, "array<") && strings.HasSuffix(typ, ">") {
+		return avroArray{Type: "array", Items: avroLeafType(typ[len("array<"):len(typ)-1], path+"[]", opts)}
+	}
+	switch typ {
+	case "string":
+		return "string"
+	case "number":
+		return "double"
+	case "boolean":
+		return "boolean"
+	case "unknown":
+		return "null"
+	default:
+		opts.warn(path, fmt.Sprintf("type %s has no Avro equivalent; written as string", typ))
+		return "string"
+	}
+}
+
+// avroName rewrites key into a valid Avro name: [A-Za-z_][A-Za-z0-9_]*.
+func avroName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// uniqueNames maps keys to the names sanitize rewrites them into. Keys that
+// are valid names keep them; when the names of others collide, as "a-b" and
+// "a_b" do, they are numbered: "a_b_2".
+func uniqueNames(keys []string, sanitize func(string) string) map[string]string {
+	names := make(map[string]string, len(keys))
+	taken := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if sanitize(key) == key {
+			names[key] = key
+			taken[key] = true
+		}
+	}
+	for _, key := range keys {
+		if _, ok := names[key]; ok {
+			continue
+		}
+		base := sanitize(key)
+		name := base
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		names[key] = name
+		taken[name] = true
+	}
+	return names
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWriteAvro(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{
+			"name": "Alice",
+			"age":  30.0,
+			"tags": []interface{}{
+				map[string]interface{}{"id": 1.0},
+			},
+		},
+		map[string]interface{}{"name": "Bob", "avatar": nil},
+	}
+
+	var buf bytes.Buffer
+	opts := &renderOptions{}
+	if err := writeAvro(&buf, analyzeJSON(data), opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", opts.warnings)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if schema["type"] != "record" || schema["name"] != "root" {
+		t.Errorf("unexpected root record: %v", schema)
+	}
+
+	byName := make(map[string]map[string]interface{})
+	for _, f := range schema["fields"].([]interface{}) {
+		field := f.(map[string]interface{})
+		byName[field["name"].(string)] = field
+	}
+
+	if byName["name"]["type"] != "string" {
+		t.Errorf("name should be a plain string, got %v", byName["name"]["type"])
+	}
+	if _, ok := byName["name"]["default"]; ok {
+		t.Error("required field should not have a default")
+	}
+
+	age, ok := byName["age"]["type"].([]interface{})
+	if !ok || len(age) != 2 || age[0] != "null" || age[1] != "double" {
+		t.Errorf("age should be a nullable double union, got %v", byName["age"]["type"])
+	}
+	if v, ok := byName["age"]["default"]; !ok || v != nil {
+		t.Error("optional field should default to null")
+	}
+
+	if byName["avatar"]["type"] != "null" {
+		t.Errorf("always-null field should be null, got %v", byName["avatar"]["type"])
+	}
+
+	tags := byName["tags"]["type"].([]interface{})[1].(map[string]interface{})
+	if tags["type"] != "array" {
+		t.Fatalf("tags should be an array, got %v", tags)
+	}
+	items := tags["items"].(map[string]interface{})
+	if items["type"] != "record" || items["name"] != "root_tags" {
+		t.Errorf("tags items should be a record named root_tags, got %v", items)
+	}
+}
+
+func TestWriteAvroRecordNames(t *testing.T) {
+	var data interface{}
+	json.Unmarshal([]byte(`{"a": {"b_c": {"x": 1}}, "a_b": {"c": {"y": 1}}}`), &data)
+
+	var buf bytes.Buffer
+	if err := writeAvro(&buf, analyzeJSON(data), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var schema interface{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	var names []string
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if v["type"] == "record" {
+				names = append(names, v["name"].(string))
+			}
+			for _, child := range v {
+				collect(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				collect(child)
+			}
+		}
+	}
+	collect(schema)
+
+	sort.Strings(names)
+	expected := []string{"root", "root_a", "root_a_b", "root_a_b_c", "root_a_b_c_2"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("record names = %v; want %v", names, expected)
+	}
+}
+
+func TestWriteAvroWarnings(t *testing.T) {
+	data := map[string]interface{}{
+		"first-name": "Alice",
+		"matrix":     []interface{}{[]interface{}{map[string]interface{}{"x": 1.0}}},
+	}
+
+	var buf bytes.Buffer
+	opts := &renderOptions{}
+	if err := writeAvro(&buf, analyzeJSON(data), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`first-name: not a valid Avro name; renamed to "first_name"`,
+		"matrix[]: type array has no Avro equivalent; written as string",
+	}
+	if strings.Join(opts.warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("warnings = %q; want %q", opts.warnings, expected)
+	}
+}
+
+func TestAvroName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"name", "name"},
+		{"first-name", "first_name"},
+		{"2fa", "_2fa"},
+		{"user id", "user_id"},
+		{"", "_"},
+	}
+
+	for _, tt := range tests {
+		if result := avroName(tt.input); result != tt.expected {
+			t.Errorf("avroName(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	keys := []string{"a b", "a-b", "a_b", "a_b_2", "c"}
+	names := uniqueNames(keys, avroName)
+	expected := map[string]string{"a b": "a_b_3", "a-b": "a_b_4", "a_b": "a_b", "a_b_2": "a_b_2", "c": "c"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("uniqueNames() = %v; want %v", names, expected)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const azureAPIVersion = "2021-08-06"
+
+// Endpoints used to reach Azure; variables so tests can substitute servers.
+var (
+	azureBlobEndpoint = "https://%s.blob.core.windows.net"
+	azureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// azureAuth authorizes Blob Storage requests with a SAS token or a managed
+// identity bearer token. Without either, requests are sent anonymously,
+// which works for public containers.
+type azureAuth struct {
+	sas   url.Values
+	token string
+}
+
+func isAzureBlobURL(input string) bool {
+	u, err := url.Parse(input)
+	return err == nil && u.Scheme == "https" && strings.HasSuffix(u.Host, ".blob.core.windows.net")
+}
+
+// azureSources resolves az://account/container/prefix to every blob under
+// the prefix, and an https://account.blob.core.windows.net/container/blob
+// URL to that single blob.
+func azureSources(input string) ([]source, error) {
+	u, err := url.Parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var account, endpoint string
+	if u.Scheme == "az" {
+		account = u.Host
+		endpoint = fmt.Sprintf(azureBlobEndpoint, account)
+	} else {
+		account = strings.TrimSuffix(u.Host, ".blob.core.windows.net")
+		endpoint = "https://" + u.Host
+	}
+	container, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if account == "" || container == "" {
+		return nil, fmt.Errorf("invalid Azure location %q (want az://account/container/prefix)", input)
+	}
+
+	auth, err := newAzureAuth(u.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if u.Scheme == "az" {
+		names, err = auth.listBlobs(endpoint, container, prefix)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no blobs found under %s", input)
+		}
+	} else {
+		names = []string{prefix}
+	}
+
+	sources := make([]source, 0, len(names))
+	for _, name := range names {
+		blobURL := endpoint + "/" + container + "/" + (&url.URL{Path: name}).EscapedPath()
+		sources = append(sources, source{
+			name: fmt.Sprintf("az://%s/%s/%s", account, container, name),
+			open: func() (io.ReadCloser, error) {
+				resp, err := auth.get(blobURL, nil)
+				if err != nil {
+					return nil, fmt.Errorf("fetching blob %s: %v", name, err)
+				}
+				return resp.Body, nil
+			},
+		})
+	}
+	return sources, nil
+}
+
+// newAzureAuth uses the SAS token in query (from a pre-signed URL) or in
+// AZURE_STORAGE_SAS_TOKEN, falling back to a managed identity token when
+// AZURE_MANAGED_IDENTITY or AZURE_CLIENT_ID asks for one.
+func newAzureAuth(query url.Values) (*azureAuth, error) {
+	if query.Get("sig") != "" {
+		return &azureAuth{sas: query}, nil
+	}
+	if token := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); token != "" {
+		sas, err := url.ParseQuery(strings.TrimPrefix(token, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid AZURE_STORAGE_SAS_TOKEN: %v", err)
+		}
+		return &azureAuth{sas: sas}, nil
+	}
+
+	// Outside Azure, probing the metadata service takes until it times out,
+	// so it is left to runs that ask for an identity.
+	if os.Getenv("AZURE_MANAGED_IDENTITY") == "" && os.Getenv("AZURE_CLIENT_ID") == "" {
+		return &azureAuth{}, nil
+	}
+	token, err := managedIdentityToken()
+	if err != nil {
+		return nil, fmt.Errorf("managed identity: %v", err)
+	}
+	return &azureAuth{token: token}, nil
+}
+
+// managedIdentityToken requests a Storage token from the instance metadata
+// service. AZURE_CLIENT_ID selects a user-assigned identity.
+func managedIdentityToken() (string, error) {
+	params := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {"https://storage.azure.com/"},
+	}
+	if clientID := os.Getenv("AZURE_CLIENT_ID"); clientID != "" {
+		params.Set("client_id", clientID)
+	}
+	req, err := http.NewRequest("GET", azureIMDSEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	return body.AccessToken, nil
+}
+
+// get performs an authorized GET of rawURL with the extra query parameters.
+func (a *azureAuth) get(rawURL string, params url.Values) (*http.Response, error) {
+	query := url.Values{}
+	for k, v := range a.sas {
+		query[k] = v
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) && a.sas == nil && a.token == "" {
+			return nil, fmt.Errorf("status %d (set AZURE_STORAGE_SAS_TOKEN, or AZURE_MANAGED_IDENTITY=1 to use a managed identity)", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// listBlobs returns the names of the blobs in container starting with
+// prefix, following continuation markers across pages.
+func (a *azureAuth) listBlobs(endpoint, container, prefix string) ([]string, error) {
+	var names []string
+	marker := ""
+	for {
+		params := url.Values{"restype": {"container"}, "comp": {"list"}}
+		if prefix != "" {
+			params.Set("prefix", prefix)
+		}
+		if marker != "" {
+			params.Set("marker", marker)
+		}
+
+		resp, err := a.get(endpoint+"/"+container, params)
+		if err != nil {
+			return nil, fmt.Errorf("listing blobs: %v", err)
+		}
+		var page struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listing blobs: %v", err)
+		}
+
+		for _, blob := range page.Blobs {
+			names = append(names, blob.Name)
+		}
+		if page.NextMarker == "" {
+			return names, nil
+		}
+		marker = page.NextMarker
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAzureSources(t *testing.T) {
+	blobs := map[string]string{
+		"exports/2024/a.json": `{"id": 1, "name": "a"}`,
+		"exports/2024/b.json": `{"id": 2}`,
+		"other/c.json":        `{"x": true}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sig") != "secret" || r.Header.Get("x-ms-version") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/data" && r.URL.Query().Get("comp") == "list" {
+			prefix := r.URL.Query().Get("prefix")
+			fmt.Fprint(w, "<EnumerationResults><Blobs>")
+			for _, name := range []string{"exports/2024/a.json", "exports/2024/b.json", "other/c.json"} {
+				if strings.HasPrefix(name, prefix) {
+					fmt.Fprintf(w, "<Blob><Name>%s</Name></Blob>", name)
+				}
+			}
+			fmt.Fprint(w, "</Blobs><NextMarker/></EnumerationResults>")
+			return
+		}
+		body, ok := blobs[strings.TrimPrefix(r.URL.Path, "/data/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	oldEndpoint := azureBlobEndpoint
+	azureBlobEndpoint = server.URL + "%.0s"
+	defer func() { azureBlobEndpoint = oldEndpoint }()
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2021-08-06&sig=secret")
+
+	sources, err := resolveSources("az://acct/data/exports/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[0].name != "az://acct/data/exports/2024/a.json" {
+		t.Fatalf("unexpected sources: %+v", sources)
+	}
+
+	var docs []interface{}
+	for _, src := range sources {
+		r, err := src.open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := newJSONDecoder(r, nil).Decode()
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+
+	fields := analyzeJSON(mergeDocuments(docs))
+	if fields["id"].Optional || !fields["name"].Optional {
+		t.Errorf("blobs should be merged into one shape: id optional=%v, name optional=%v",
+			fields["id"].Optional, fields["name"].Optional)
+	}
+
+	if _, err := resolveSources("az://acct/data/missing/"); err == nil {
+		t.Error("expected error when no blobs match the prefix")
+	}
+}
+
+func TestManagedIdentityToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("resource") != "https://storage.azure.com/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, `{"access_token": "tok-`+r.URL.Query().Get("client_id")+`"}`)
+	}))
+	defer server.Close()
+
+	oldEndpoint := azureIMDSEndpoint
+	azureIMDSEndpoint = server.URL
+	defer func() { azureIMDSEndpoint = oldEndpoint }()
+	t.Setenv("AZURE_CLIENT_ID", "abc")
+
+	token, err := managedIdentityToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "tok-abc" {
+		t.Errorf("token = %q; want %q", token, "tok-abc")
+	}
+}
+
+func TestNewAzureAuth(t *testing.T) {
+	probes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		if r.URL.Query().Get("client_id") == "missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, `{"access_token": "tok"}`)
+	}))
+	defer server.Close()
+
+	oldEndpoint := azureIMDSEndpoint
+	azureIMDSEndpoint = server.URL
+	defer func() { azureIMDSEndpoint = oldEndpoint }()
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "")
+	t.Setenv("AZURE_MANAGED_IDENTITY", "")
+	t.Setenv("AZURE_CLIENT_ID", "")
+
+	auth, err := newAzureAuth(nil)
+	if err != nil || auth.token != "" || probes != 0 {
+		t.Errorf("without a managed identity asked for: auth %+v, error %v, %d probes; want anonymous access without probing", auth, err, probes)
+	}
+
+	t.Setenv("AZURE_MANAGED_IDENTITY", "1")
+	if auth, err = newAzureAuth(nil); err != nil || auth.token != "tok" {
+		t.Errorf("with AZURE_MANAGED_IDENTITY: auth %+v, error %v; want the identity's token", auth, err)
+	}
+
+	t.Setenv("AZURE_CLIENT_ID", "missing")
+	if _, err = newAzureAuth(nil); err == nil || !strings.Contains(err.Error(), "managed identity: status 400") {
+		t.Errorf("error = %v; want the failed token request reported", err)
+	}
+}
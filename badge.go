@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const badgeLabel = "json shape"
+
+// badge is the status a shape badge shows: how many fields the shape has,
+// when it was verified and, given a baseline, whether it drifted.
+type badge struct {
+	message string
+	color   string
+}
+
+// shapeBadge summarizes fields as of now. With a baseline, the badge also
+// says how many fields were added, removed or changed since.
+func shapeBadge(fields map[string]*FieldInfo, opts *renderOptions) badge {
+	lines := shapeLines(fields)
+	noun := "fields"
+	if len(lines) == 1 {
+		noun = "field"
+	}
+	parts := []string{fmt.Sprintf("%d %s", len(lines), noun)}
+	color := "blue"
+	if opts.baseline != nil {
+		switch n := driftCount(opts.baseline, lines); n {
+		case 0:
+			parts = append(parts, "no drift")
+			color = "brightgreen"
+		case 1:
+			parts = append(parts, "drifted (1 change)")
+			color = "orange"
+		default:
+			parts = append(parts, fmt.Sprintf("drifted (%d changes)", n))
+			color = "orange"
+		}
+	}
+	now := opts.now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	parts = append(parts, "verified "+now.UTC().Format(time.DateOnly))
+	return badge{message: strings.Join(parts, " · "), color: color}
+}
+
+// writeBadge writes the shape's badge as a shields.io endpoint payload, for
+// a README to embed with https://img.shields.io/endpoint?url=<payload URL>.
+func writeBadge(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	b := shapeBadge(fields, opts)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		Label         string `json:"label"`
+		Message       string `json:"message"`
+		Color         string `json:"color"`
+	}{1, badgeLabel, b.message, b.color})
+}
+
+// badgeColors maps the shields.io colors badges use to their RGB values.
+var badgeColors = map[string]string{
+	"blue":        "#007ec6",
+	"brightgreen": "#4c1",
+	"orange":      "#fe7d37",
+}
+
+// writeBadgeSVG writes the shape's badge as a self-contained SVG image in
+// the flat shields.io style, for READMEs that embed a committed file.
+func writeBadgeSVG(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	b := shapeBadge(fields, opts)
+	label, message := badgeTextWidth(badgeLabel), badgeTextWidth(b.message)
+	width := label + message
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+  <title>%s: %s</title>
+  <rect width="%d" height="20" fill="#555"/>
+  <rect x="%d" width="%d" height="20" fill="%s"/>
+  <g fill="#fff" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11" text-anchor="middle">
+    <text x="%d" y="14">%s</text>
+    <text x="%d" y="14">%s</text>
+  </g>
+</svg>
+`,
+		width, badgeLabel, html.EscapeString(b.message),
+		badgeLabel, html.EscapeString(b.message),
+		label,
+		label, message, badgeColors[b.color],
+		label/2, badgeLabel,
+		label+message/2, html.EscapeString(b.message))
+	return err
+}
+
+// badgeTextWidth approximates the width in pixels of text set in 11px
+// Verdana, with padding on both sides.
+func badgeTextWidth(text string) int {
+	return utf8.RuneCountInString(text)*7 + 10
+}
+
+// shapeLines flattens fields into one "path:type" line per field, in the
+// notation of the compact format, so that shapes can be compared with a
+// baseline saved with --format=compact.
+func shapeLines(fields map[string]*FieldInfo) map[string]string {
+	lines := make(map[string]string)
+	var walk func(fields map[string]*FieldInfo, parent string)
+	walk = func(fields map[string]*FieldInfo, parent string) {
+		for key, field := range fields {
+			path := fieldPath(parent, key)
+			lines[path] = compactFieldType(field)
+			walk(field.Children, path)
+		}
+	}
+	walk(fields, "")
+	return lines
+}
+
+// shapeBaseline is a shape saved with --format=compact, in the lines of
+// shapeLines. A partial baseline was written under --budget: the fields
+// missing from it may have been left out.
+type shapeBaseline struct {
+	lines   map[string]string
+	partial bool
+}
+
+// driftCount returns the number of fields added, removed or changed in
+// type or presence between the baseline and current shapes. Fields missing
+// from a partial baseline are not counted as added.
+func driftCount(baseline *shapeBaseline, current map[string]string) int {
+	n := 0
+	for path, typ := range current {
+		old, ok := baseline.lines[path]
+		if ok && old != typ || !ok && !baseline.partial {
+			n++
+		}
+	}
+	for path := range baseline.lines {
+		if _, ok := current[path]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// readBaseline reads a shape written with --format=compact.
+func readBaseline(name string) (*shapeBaseline, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseCompactShape(f)
+}
+
+func parseCompactShape(r io.Reader) (*shapeBaseline, error) {
+	baseline := &shapeBaseline{lines: make(map[string]string)}
+	var parents []string // path of the last field at each depth
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		var omitted int
+		if _, err := fmt.Sscanf(line+"\n", omittedNoteFormat, &omitted); err == nil {
+			baseline.partial = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		depth := (len(line) - len(trimmed)) / 2
+		key, typ, ok := cutCompactLine(trimmed)
+		if !ok || depth > len(parents) {
+			return nil, fmt.Errorf("line %d: not compact shape output: %q", n, line)
+		}
+		parent := ""
+		if depth > 0 {
+			parent = parents[depth-1]
+		}
+		parents = append(parents[:depth], fieldPath(parent, key))
+		baseline.lines[parents[depth]] = typ
+	}
+	return baseline, scanner.Err()
+}
+
+// cutCompactLine splits an unindented compact line into its key and type.
+func cutCompactLine(line string) (key, typ string, ok bool) {
+	if strings.HasPrefix(line, `["`) {
+		segment, n, err := parseBracket(line)
+		if err != nil || !strings.HasPrefix(line[n:], ":") {
+			return "", "", false
+		}
+		return segment.key, line[n+1:], true
+	}
+	// Bare keys written before keys with colons were quoted may hold
+	// colons; types do not.
+	colon := strings.LastIndex(line, ":")
+	if colon < 0 {
+		return "", "", false
+	}
+	return line[:colon], line[colon+1:], true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShapeBadge(t *testing.T) {
+	fields := analyzeJSON([]interface{}{
+		map[string]interface{}{"id": 1.0, "user": map[string]interface{}{"name": "a"}},
+		map[string]interface{}{"id": 2.0, "user": map[string]interface{}{"name": nil}},
+	})
+	now := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		baseline    string
+		wantMessage string
+		wantColor   string
+	}{
+		{"no baseline", "", "3 fields · verified 2026-03-01", "blue"},
+		{"unchanged", "id:number\nuser:object\n  name:string|null\n", "3 fields · no drift · verified 2026-03-01", "brightgreen"},
+		{"changed", "id:string\nuser:object\n  name:string|null\n", "3 fields · drifted (1 change) · verified 2026-03-01", "orange"},
+		{"added and removed", "id:number\nold:boolean?\nuser:object\n", "3 fields · drifted (2 changes) · verified 2026-03-01", "orange"},
+		{"partial", "id:number\nold:boolean?\n# 2 rare fields omitted\n", "3 fields · drifted (1 change) · verified 2026-03-01", "orange"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &renderOptions{now: now}
+			if tt.baseline != "" {
+				baseline, err := parseCompactShape(strings.NewReader(tt.baseline))
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts.baseline = baseline
+			}
+			b := shapeBadge(fields, opts)
+			if b.message != tt.wantMessage || b.color != tt.wantColor {
+				t.Errorf("shapeBadge() = %q %s, want %q %s", b.message, b.color, tt.wantMessage, tt.wantColor)
+			}
+		})
+	}
+}
+
+func TestWriteBadge(t *testing.T) {
+	fields := analyzeJSON(map[string]interface{}{"id": 1.0})
+	opts := &renderOptions{now: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+
+	var buf bytes.Buffer
+	if err := writeBadge(&buf, fields, opts); err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &payload); err != nil {
+		t.Fatalf("badge is not JSON: %v\n%s", err, buf.String())
+	}
+	if payload["schemaVersion"] != 1.0 || payload["label"] != badgeLabel || payload["message"] != "1 field · verified 2026-03-01" || payload["color"] != "blue" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+
+	buf.Reset()
+	if err := writeBadgeSVG(&buf, fields, opts); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	for _, want := range []string{"<svg ", `fill="#007ec6"`, ">1 field · verified 2026-03-01</text>"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG badge lacks %q:\n%s", want, svg)
+		}
+	}
+}
+
+func TestParseCompactShape(t *testing.T) {
+	got, err := parseCompactShape(strings.NewReader("a:number\nb:object[]?\n  c:string\n  d:object\n    e:boolean\nf:x:string\n# 2 rare fields omitted\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "number", "b": "object[]?", "b.c": "string", "b.d": "object", "b.d.e": "boolean", "f:x": "string"}
+	if len(got.lines) != len(want) {
+		t.Errorf("parseCompactShape() = %v, want %v", got.lines, want)
+	}
+	for path, typ := range want {
+		if got.lines[path] != typ {
+			t.Errorf("%s: got %q, want %q", path, got.lines[path], typ)
+		}
+	}
+	if !got.partial {
+		t.Error("expected a shape with omitted fields to be partial")
+	}
+	if got, err := parseCompactShape(strings.NewReader("a:number\n")); err != nil || got.partial {
+		t.Errorf("parseCompactShape() = %+v, %v; want a complete shape", got, err)
+	}
+
+	if _, err := parseCompactShape(strings.NewReader("root\n├── a: number\n")); err == nil {
+		t.Error("expected an error for tree output")
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+var errBSONTruncated = errors.New("truncated document")
+
+// bsonDecoder decodes a stream of concatenated BSON documents, as written by
+// mongodump. ObjectIds become hex strings, datetimes RFC 3339 strings, UUID
+// binaries UUID strings and other binaries base64 strings.
+type bsonDecoder struct {
+	r io.Reader
+}
+
+func newBSONDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return &bsonDecoder{r: r}
+}
+
+func (d *bsonDecoder) Decode() (interface{}, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("bson: %w", unexpectedEOF(err))
+	}
+	n := binary.LittleEndian.Uint32(size[:])
+	if n < 5 {
+		return nil, fmt.Errorf("bson: invalid document size %d", n)
+	}
+	rest, err := readBytes(d.r, uint64(n-4))
+	if err != nil {
+		return nil, fmt.Errorf("bson: %w", err)
+	}
+
+	c := &bsonCursor{buf: append(size[:], rest...)}
+	doc, err := c.document(0)
+	if err != nil {
+		return nil, fmt.Errorf("bson: %w", err)
+	}
+	return doc, nil
+}
+
+// bsonCursor reads BSON values from an in-memory document.
+type bsonCursor struct {
+	buf []byte
+	pos int
+}
+
+func (c *bsonCursor) next(n int) ([]byte, error) {
+	if n < 0 || c.pos+n > len(c.buf) {
+		return nil, errBSONTruncated
+	}
+	b := c.buf[c.pos : c.pos+n]
+	c.pos += n
+	return b, nil
+}
+
+func (c *bsonCursor) int32() (int32, error) {
+	b, err := c.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(b)), nil
+}
+
+func (c *bsonCursor) uint64() (uint64, error) {
+	b, err := c.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (c *bsonCursor) cstring() (string, error) {
+	for i := c.pos; i < len(c.buf); i++ {
+		if c.buf[i] == 0 {
+			s := string(c.buf[c.pos:i])
+			c.pos = i + 1
+			return s, nil
+		}
+	}
+	return "", errBSONTruncated
+}
+
+func (c *bsonCursor) string() (string, error) {
+	n, err := c.int32()
+	if err != nil {
+		return "", err
+	}
+	b, err := c.next(int(n))
+	if err != nil || n < 1 {
+		return "", errBSONTruncated
+	}
+	return string(b[:n-1]), nil
+}
+
+// document reads an embedded document as a map.
+func (c *bsonCursor) document(depth int) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	err := c.elements(depth, func(key string, v interface{}) {
+		doc[key] = v
+	})
+	return doc, err
+}
+
+// array reads an embedded array, whose keys are the indexes "0", "1", ....
+func (c *bsonCursor) array(depth int) ([]interface{}, error) {
+	arr := []interface{}{}
+	err := c.elements(depth, func(key string, v interface{}) {
+		arr = append(arr, v)
+	})
+	return arr, err
+}
+
+func (c *bsonCursor) elements(depth int, add func(string, interface{})) error {
+	if depth > maxDecodeDepth {
+		return fmt.Errorf("nesting exceeds %d levels", maxDecodeDepth)
+	}
+	start := c.pos
+	size, err := c.int32()
+	if err != nil {
+		return err
+	}
+	end := start + int(size)
+	if size < 5 || end > len(c.buf) {
+		return errBSONTruncated
+	}
+
+	for c.pos < end-1 {
+		typ, err := c.next(1)
+		if err != nil {
+			return err
+		}
+		key, err := c.cstring()
+		if err != nil {
+			return err
+		}
+		v, err := c.value(typ[0], depth)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		add(key, v)
+	}
+	if c.pos != end-1 || c.buf[c.pos] != 0 {
+		return errBSONTruncated
+	}
+	c.pos = end
+	return nil
+}
+
+func (c *bsonCursor) value(typ byte, depth int) (interface{}, error) {
+	switch typ {
+	case 0x01:
+		n, err := c.uint64()
+		return floatValue(math.Float64frombits(n)), err
+	case 0x02, 0x0D, 0x0E:
+		return c.string()
+	case 0x03:
+		return c.document(depth + 1)
+	case 0x04:
+		return c.array(depth + 1)
+	case 0x05:
+		n, err := c.int32()
+		if err != nil {
+			return nil, err
+		}
+		subtype, err := c.next(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := c.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		if (subtype[0] == 0x03 || subtype[0] == 0x04) && len(b) == 16 {
+			h := hex.EncodeToString(b)
+			return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case 0x06, 0x0A, 0x7F, 0xFF:
+		// undefined, null, MaxKey and MinKey carry no value.
+		return nil, nil
+	case 0x07:
+		b, err := c.next(12)
+		return hex.EncodeToString(b), err
+	case 0x08:
+		b, err := c.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case 0x09:
+		n, err := c.uint64()
+		return time.UnixMilli(int64(n)).UTC().Format(time.RFC3339Nano), err
+	case 0x0B:
+		pattern, err := c.cstring()
+		if err != nil {
+			return nil, err
+		}
+		options, err := c.cstring()
+		return "/" + pattern + "/" + options, err
+	case 0x0C:
+		ns, err := c.string()
+		if err != nil {
+			return nil, err
+		}
+		id, err := c.next(12)
+		return ns + "." + hex.EncodeToString(id), err
+	case 0x0F:
+		start := c.pos
+		size, err := c.int32()
+		if err != nil {
+			return nil, err
+		}
+		code, err := c.string()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.document(depth + 1); err != nil {
+			return nil, err
+		}
+		if c.pos != start+int(size) {
+			return nil, errBSONTruncated
+		}
+		return code, nil
+	case 0x10:
+		n, err := c.int32()
+		return float64(n), err
+	case 0x11, 0x12:
+		n, err := c.uint64()
+		if typ == 0x11 {
+			return float64(n), err
+		}
+		return float64(int64(n)), err
+	case 0x13:
+		low, err := c.uint64()
+		if err != nil {
+			return nil, err
+		}
+		high, err := c.uint64()
+		return decimal128(high, low), err
+	}
+	return nil, fmt.Errorf("unsupported element type 0x%02x", typ)
+}
+
+// decimal128 converts an IEEE 754-2008 BID-encoded decimal to float64.
+func decimal128(high, low uint64) float64 {
+	sign := 1.0
+	if high>>63 == 1 {
+		sign = -1
+	}
+	if (high>>61)&3 == 3 {
+		// Infinity, NaN, or a non-canonical coefficient, which is zero.
+		switch (high >> 58) & 0x1f {
+		case 0x1e:
+			return math.Inf(int(sign))
+		case 0x1f:
+			return math.NaN()
+		}
+		return 0
+	}
+
+	exp := int64((high>>49)&0x3fff) - 6176
+	coef := new(big.Int).SetUint64(high & (1<<49 - 1))
+	coef.Lsh(coef, 64).Or(coef, new(big.Int).SetUint64(low))
+	f, _ := strconv.ParseFloat(coef.String()+"e"+strconv.FormatInt(exp, 10), 64)
+	return sign * f
+}
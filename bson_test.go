@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func bsonDoc(elements ...[]byte) []byte {
+	body := bytes.Join(elements, nil)
+	doc := binary.LittleEndian.AppendUint32(nil, uint32(len(body)+5))
+	doc = append(doc, body...)
+	return append(doc, 0)
+}
+
+func bsonElem(typ byte, key string, payload []byte) []byte {
+	elem := append([]byte{typ}, key...)
+	elem = append(elem, 0)
+	return append(elem, payload...)
+}
+
+func bsonString(s string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(s)+1))
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+func TestBSONDecoder(t *testing.T) {
+	// {"hello": "world"}, from the BSON specification.
+	hello := []byte("\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00world\x00\x00")
+
+	doc := bsonDoc(
+		bsonElem(0x07, "_id", []byte{0x50, 0x7f, 0x1f, 0x77, 0xbc, 0xf8, 0x6c, 0xd7, 0x99, 0x43, 0x90, 0x11}),
+		bsonElem(0x01, "score", binary.LittleEndian.AppendUint64(nil, math.Float64bits(1.5))),
+		bsonElem(0x01, "ratio", binary.LittleEndian.AppendUint64(nil, math.Float64bits(math.Inf(1)))),
+		bsonElem(0x10, "age", binary.LittleEndian.AppendUint32(nil, 30)),
+		bsonElem(0x12, "big", binary.LittleEndian.AppendUint64(nil, 1<<40)),
+		bsonElem(0x08, "active", []byte{1}),
+		bsonElem(0x0A, "deleted", nil),
+		bsonElem(0x09, "created", binary.LittleEndian.AppendUint64(nil, 86400000)),
+		bsonElem(0x03, "nested", hello),
+		bsonElem(0x04, "tags", bsonDoc(
+			bsonElem(0x02, "0", bsonString("a")),
+			bsonElem(0x02, "1", bsonString("b")),
+		)),
+		bsonElem(0x05, "uuid", append([]byte{16, 0, 0, 0, 4}, bytes.Repeat([]byte{0xab}, 16)...)),
+		bsonElem(0x13, "price", append(binary.LittleEndian.AppendUint64(nil, 1234), 0, 0, 0, 0, 0, 0, 0x3c, 0x30)),
+	)
+
+	dec := newBSONDecoder(bytes.NewReader(append(doc, hello...)), nil)
+	result, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"_id":     "507f1f77bcf86cd799439011",
+		"score":   1.5,
+		"ratio":   "Infinity",
+		"age":     30.0,
+		"big":     float64(1 << 40),
+		"active":  true,
+		"deleted": nil,
+		"created": "1970-01-02T00:00:00Z",
+		"nested":  map[string]interface{}{"hello": "world"},
+		"tags":    []interface{}{"a", "b"},
+		"uuid":    "abababab-abab-abab-abab-abababababab",
+		"price":   12.34,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Decode() = %#v\nwant %#v", result, expected)
+	}
+
+	if second, err := dec.Decode(); err != nil || !reflect.DeepEqual(second, map[string]interface{}{"hello": "world"}) {
+		t.Errorf("second document = %#v, %v", second, err)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected io.EOF after last document, got %v", err)
+	}
+}
+
+func TestBSONDecoderTruncated(t *testing.T) {
+	input := []byte("\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00wor")
+	if _, err := newBSONDecoder(bytes.NewReader(input), nil).Decode(); err == nil || err == io.EOF {
+		t.Errorf("expected error for truncated input, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+)
+
+// errCBORBreak signals the "break" stop code ending an indefinite-length item.
+var errCBORBreak = errors.New("unexpected break")
+
+// cborDecoder decodes a stream of CBOR data items. Byte strings become base64
+// strings, epoch timestamps (tag 1) become RFC 3339 strings, bignums become
+// numbers and other tags are replaced by their content.
+type cborDecoder struct {
+	r *bufio.Reader
+}
+
+func newCBORDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return &cborDecoder{r: newBufferedReader(r)}
+}
+
+func (d *cborDecoder) Decode() (interface{}, error) {
+	if _, err := d.r.Peek(1); err != nil {
+		return nil, err
+	}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, fmt.Errorf("cbor: %w", unexpectedEOF(err))
+	}
+	return v, nil
+}
+
+// head reads the initial byte of a data item and its argument. Additional
+// information 31 marks an indefinite length or, for major type 7, a break.
+func (d *cborDecoder) head() (major byte, info byte, arg uint64, err error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b>>5, b&0x1f
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		buf, err := readBytes(d.r, 1<<(info-24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, c := range buf {
+			arg = arg<<8 | uint64(c)
+		}
+	case info == 31:
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid additional information %d", info)
+	}
+	return major, info, arg, nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("nesting exceeds %d levels", maxDecodeDepth)
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == 31
+
+	switch major {
+	case 0:
+		return float64(arg), nil
+	case 1:
+		return -1 - float64(arg), nil
+	case 2, 3:
+		buf, err := d.bytes(major, arg, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == 2 {
+			return base64.StdEncoding.EncodeToString(buf), nil
+		}
+		return string(buf), nil
+	case 4:
+		arr := make([]interface{}, 0, capacity(arg))
+		for i := uint64(0); indefinite || i < arg; i++ {
+			v, err := d.value(depth + 1)
+			if err == errCBORBreak && indefinite {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case 5:
+		m := make(map[string]interface{}, capacity(arg))
+		for i := uint64(0); indefinite || i < arg; i++ {
+			k, err := d.value(depth + 1)
+			if err == errCBORBreak && indefinite {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[mapKey(k)] = v
+		}
+		return m, nil
+	case 6:
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag(arg, v), nil
+	}
+
+	// Major type 7: simple values and floats.
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return floatValue(halfFloat(uint16(arg))), nil
+	case 26:
+		return floatValue(float64(math.Float32frombits(uint32(arg)))), nil
+	case 27:
+		return floatValue(math.Float64frombits(arg)), nil
+	case 31:
+		return nil, errCBORBreak
+	}
+	// Unassigned simple values carry no type information.
+	return nil, nil
+}
+
+// bytes reads the content of a byte or text string, concatenating the chunks
+// of an indefinite-length string.
+func (d *cborDecoder) bytes(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		return readBytes(d.r, n)
+	}
+	var out []byte
+	for {
+		chunkMajor, info, arg, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == 7 && info == 31 {
+			return out, nil
+		}
+		if chunkMajor != major || info == 31 {
+			return nil, errors.New("invalid indefinite-length string chunk")
+		}
+		chunk, err := readBytes(d.r, arg)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+}
+
+func cborTag(tag uint64, v interface{}) interface{} {
+	switch tag {
+	case 1:
+		if secs, ok := v.(float64); ok {
+			sec, frac := math.Modf(secs)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+		}
+	case 2, 3:
+		if s, ok := v.(string); ok {
+			buf, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return v
+			}
+			f, _ := new(big.Float).SetInt(new(big.Int).SetBytes(buf)).Float64()
+			if tag == 3 {
+				return -1 - f
+			}
+			return f
+		}
+	}
+	return v
+}
+
+// halfFloat converts an IEEE 754 half-precision float to float64.
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
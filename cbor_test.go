@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCBORDecoder(t *testing.T) {
+	// Examples from RFC 8949, Appendix A.
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"a26161016162820203", map[string]interface{}{"a": 1.0, "b": []interface{}{2.0, 3.0}}},
+		{"3903e7", -1000.0},
+		{"f93c00", 1.0},
+		{"f9c400", -4.0},
+		{"fa47c35000", 100000.0},
+		{"f97e00", "NaN"},
+		{"f97c00", "Infinity"},
+		{"fbfff0000000000000", "-Infinity"},
+		{"f4", false},
+		{"f6", nil},
+		{"c11a514b67b0", "2013-03-21T20:04:00Z"},
+		{"c249010000000000000000", 18446744073709551616.0},
+		{"4401020304", "AQIDBA=="},
+		{"5f42010243030405ff", "AQIDBAU="},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []interface{}{1.0, []interface{}{2.0, 3.0}, []interface{}{4.0, 5.0}}},
+		{"bf61610161629f0203ffff", map[string]interface{}{"a": 1.0, "b": []interface{}{2.0, 3.0}}},
+		{"a201020304", map[string]interface{}{"1": 2.0, "3": 4.0}},
+	}
+
+	for _, tt := range tests {
+		input, _ := hex.DecodeString(tt.input)
+		result, err := newCBORDecoder(bytes.NewReader(input), nil).Decode()
+		if err != nil {
+			t.Errorf("Decode(%s) error: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("Decode(%s) = %#v; want %#v", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestCBORDecoderStream(t *testing.T) {
+	input, _ := hex.DecodeString("a1616101a1616102")
+	dec := newCBORDecoder(bytes.NewReader(input), nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("document %d: %v", i, err)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected io.EOF after last document, got %v", err)
+	}
+}
+
+func TestCBORDecoderInvalid(t *testing.T) {
+	for _, input := range []string{"a2616101", "ff", "1c", "5bffffffffffffffff", "7b8000000000000000"} {
+		b, _ := hex.DecodeString(input)
+		if _, err := newCBORDecoder(bytes.NewReader(b), nil).Decode(); err == nil || err == io.EOF {
+			t.Errorf("Decode(%s): expected error, got %v", input, err)
+		}
+	}
+}
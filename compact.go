@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writeCompact writes one "name:type" line per field, indenting children by
+// two spaces, marking nullable types with "|null", optional fields with a
+// trailing "?" and fields whose presence was assumed from a single object
+// with a trailing "~". Names that are not bare keys are quoted as in paths.
+// With a budget, the rarest fields are left out until the output fits in
+// budget bytes, and a note counting them ends the output.
+func writeCompact(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	omit := make(map[*FieldInfo]bool)
+	omitted := 0
+	if opts.budget > 0 {
+		omit, omitted = compactElisions(fields, opts.budget)
+	}
+
+	writeCompactLines(w, fields, "", omit)
+	if omitted > 0 {
+		fmt.Fprint(w, omittedNote(omitted))
+	}
+	return nil
+}
+
+func writeCompactLines(w io.Writer, fields map[string]*FieldInfo, indent string, omit map[*FieldInfo]bool) {
+	for _, key := range sortedKeys(fields) {
+		field := fields[key]
+		if omit[field] {
+			continue
+		}
+		fmt.Fprint(w, compactLine(key, field, indent))
+		writeCompactLines(w, field.Children, indent+"  ", omit)
+	}
+}
+
+func compactLine(key string, field *FieldInfo, indent string) string {
+	return indent + compactKey(key) + ":" + compactFieldType(field) + "\n"
+}
+
+// compactKey returns key as a compact line names it: bare when the line
+// reads back unambiguously, else quoted with quoteKey.
+func compactKey(key string) string {
+	if isBareKey(key) && !strings.Contains(key, ":") && !strings.HasPrefix(key, "#") {
+		return key
+	}
+	return quoteKey(key)
+}
+
+// compactFieldType returns the type of field as a compact line shows it,
+// with its nullable, optional and assumed markers.
+func compactFieldType(field *FieldInfo) string {
+	typ := compactType(displayType(field))
+	if field.Nullable && field.Type != "unknown" {
+		typ += "|null"
+	}
+	if field.Optional {
+		typ += "?"
+	}
+	if field.Assumed {
+		typ += "~"
+	}
+	return typ
+}
+
+// compactType shortens array types: "array<string>" becomes "string[]".
+func compactType(typ string) string {
+	if strings.HasPrefix(typ, "array<") && strings.HasSuffix(typ, ">") {
+		return compactType(typ[len("array<"):len(typ)-1]) + "[]"
+	}
+	return typ
+}
+
+type compactNode struct {
+	field    *FieldInfo
+	parent   *compactNode
+	path     string
+	depth    int
+	presence float64
+	size     int // bytes of this field's line and all its descendants
+	fields   int // this field and all its descendants
+
+	// The bytes and fields of descendants omitted so far, which omitting
+	// this field removes no more.
+	omittedSize, omittedFields int
+}
+
+// omittedNoteFormat is the note ending compact output that omits fields,
+// which marks it as a partial baseline.
+const omittedNoteFormat = "# %d rare fields omitted\n"
+
+func omittedNote(n int) string {
+	return fmt.Sprintf(omittedNoteFormat, n)
+}
+
+// compactElisions picks the fields to leave out so that the compact output,
+// including the note on omitted fields, fits in budget bytes. Fields present
+// in the smallest share of their parent objects go first, deeper ones before
+// shallower ones on ties. It returns the fields to omit and how many fields
+// that removes in total.
+func compactElisions(fields map[string]*FieldInfo, budget int) (map[*FieldInfo]bool, int) {
+	var nodes []*compactNode
+	total := collectCompactNodes(fields, nil, 0, &nodes)
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.presence != b.presence {
+			return a.presence < b.presence
+		}
+		if a.depth != b.depth {
+			return a.depth > b.depth
+		}
+		return a.path < b.path
+	})
+
+	omit := make(map[*FieldInfo]bool)
+	omitted := 0
+	for _, node := range nodes {
+		if omitted == 0 && total <= budget || omitted > 0 && total+len(omittedNote(omitted)) <= budget {
+			break
+		}
+		if hasOmittedAncestor(node, omit) {
+			continue
+		}
+		omit[node.field] = true
+		size, n := node.size-node.omittedSize, node.fields-node.omittedFields
+		total -= size
+		omitted += n
+		for p := node.parent; p != nil; p = p.parent {
+			p.omittedSize += size
+			p.omittedFields += n
+		}
+	}
+	return omit, omitted
+}
+
+// collectCompactNodes appends a node for every field under parent to nodes
+// and returns the size of their compact lines.
+func collectCompactNodes(fields map[string]*FieldInfo, parent *compactNode, depth int, nodes *[]*compactNode) int {
+	var parentField *FieldInfo
+	if parent != nil {
+		parentField = parent.field
+	}
+	objects := parentCount(parentField, fields)
+
+	parentPath := ""
+	if parent != nil {
+		parentPath = childPath(parent.path, parent.field)
+	}
+
+	total := 0
+	for _, key := range sortedKeys(fields) {
+		field := fields[key]
+		node := &compactNode{
+			field:    field,
+			parent:   parent,
+			path:     fieldPath(parentPath, key),
+			depth:    depth,
+			presence: float64(field.count) / float64(objects),
+			size:     len(compactLine(key, field, strings.Repeat("  ", depth))),
+			fields:   1,
+		}
+		*nodes = append(*nodes, node)
+
+		before := len(*nodes)
+		node.size += collectCompactNodes(field.Children, node, depth+1, nodes)
+		for _, child := range (*nodes)[before:] {
+			if child.parent == node {
+				node.fields += child.fields
+			}
+		}
+		total += node.size
+	}
+	return total
+}
+
+func hasOmittedAncestor(node *compactNode, omit map[*FieldInfo]bool) bool {
+	for p := node.parent; p != nil; p = p.parent {
+		if omit[p.field] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCompact(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{
+			"name":   "Alice",
+			"scores": []interface{}{1.0},
+			"tags": []interface{}{
+				map[string]interface{}{"id": 1.0},
+			},
+		},
+		map[string]interface{}{"name": "Bob", "scores": []interface{}{}, "nick": nil},
+		map[string]interface{}{"name": "Cy", "scores": []interface{}{}, "nick": "cy", "bio": nil},
+	}
+
+	var buf bytes.Buffer
+	if err := writeCompact(&buf, analyzeJSON(data), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "bio:unknown?\n" +
+		"name:string\n" +
+		"nick:string|null?\n" +
+		"scores:number[]\n" +
+		"tags:object[]?\n" +
+		"  id:number\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteCompactQuotedKeys(t *testing.T) {
+	data := map[string]interface{}{
+		"a:b":         1.0,
+		"line\nbreak": "x",
+		"  padded":    true,
+		"#tag":        "y",
+		"user.name":   map[string]interface{}{"first name": "z"},
+	}
+	fields := analyzeJSON(data)
+
+	var buf bytes.Buffer
+	if err := writeCompact(&buf, fields, &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `["  padded"]:boolean` + "\n" +
+		`["#tag"]:string` + "\n" +
+		`["a:b"]:number` + "\n" +
+		`["line\nbreak"]:string` + "\n" +
+		`["user.name"]:object` + "\n" +
+		`  ["first name"]:string` + "\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+
+	baseline, err := parseCompactShape(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := shapeLines(fields); !reflect.DeepEqual(baseline.lines, want) {
+		t.Errorf("read back %v, want %v", baseline.lines, want)
+	}
+}
+
+func TestWriteCompactBudget(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "a", "nick": "x", "meta": map[string]interface{}{"a": 1.0, "b": 2.0}},
+		map[string]interface{}{"id": 2.0, "name": "b", "nick": "y"},
+		map[string]interface{}{"id": 3.0, "name": "c"},
+	}
+	fields := analyzeJSON(data)
+
+	tests := []struct {
+		budget   int
+		expected string
+	}{
+		{0, "id:number\nmeta:object?\n  a:number\n  b:number\nname:string\nnick:string?\n"},
+		{100, "id:number\nmeta:object?\n  a:number\n  b:number\nname:string\nnick:string?\n"},
+		{60, "id:number\nname:string\nnick:string?\n# 3 rare fields omitted\n"},
+		{50, "id:number\nname:string\n# 4 rare fields omitted\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeCompact(&buf, fields, &renderOptions{budget: tt.budget}); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("budget %d: unexpected output:\n%s\nwant:\n%s", tt.budget, buf.String(), tt.expected)
+		}
+		if tt.budget > 0 && buf.Len() > tt.budget {
+			t.Errorf("budget %d: output is %d bytes", tt.budget, buf.Len())
+		}
+	}
+}
+
+func TestWriteCompactBudgetNested(t *testing.T) {
+	// meta.x is rarer than meta and goes first; omitting meta afterwards
+	// removes only meta and meta.y more.
+	data := []interface{}{
+		map[string]interface{}{"id": 1.0, "meta": map[string]interface{}{"x": 1.0, "y": 1.0}},
+		map[string]interface{}{"id": 2.0, "meta": map[string]interface{}{"y": 2.0}},
+		map[string]interface{}{"id": 3.0, "meta": map[string]interface{}{"y": 3.0}},
+		map[string]interface{}{"id": 4.0},
+	}
+	fields := analyzeJSON(data)
+
+	tests := []struct {
+		budget   int
+		expected string
+	}{
+		{46, "id:number\nmeta:object?\n  x:number?\n  y:number\n"},
+		{35, "id:number\n# 3 rare fields omitted\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeCompact(&buf, fields, &renderOptions{budget: tt.budget}); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("budget %d: unexpected output:\n%s\nwant:\n%s", tt.budget, buf.String(), tt.expected)
+		}
+	}
+}
+
+func TestCompactType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"string", "string"},
+		{"array<number>", "number[]"},
+		{"array<array<string>>", "string[][]"},
+		{"array<object>", "object[]"},
+	}
+
+	for _, tt := range tests {
+		if result := compactType(tt.input); result != tt.expected {
+			t.Errorf("compactType(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configFileName is the file of default flags, looked up in the working
+// directory and then its parents.
+const configFileName = ".jsonshape.yaml"
+
+// shapeConfig holds the settings of a config file as flag values by flag
+// name: the top-level settings apply to every run, and those of the profile
+// selected with --profile on top of them. Flags given on the command line
+// win over both.
+//
+//	format: markdown
+//	exclude: [metadata, "**.debug"]
+//	profiles:
+//	  ci:
+//	    quiet: true
+//	    assert-required: [id, items[].id]
+type shapeConfig struct {
+	settings map[string]string
+	profiles map[string]map[string]string
+}
+
+// findConfig returns the path of the nearest config file, or "" if there is
+// none.
+func findConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// applyConfig sets the flags of fs that were not given on the command line
+// from the config file at path, or the nearest one when path is empty, and
+// from its profile when profile is set.
+func applyConfig(fs *flag.FlagSet, path, profile string) error {
+	if path == "" {
+		path = findConfig()
+	}
+	if path == "" {
+		if profile != "" {
+			return fmt.Errorf("--profile %s: no %s found", profile, configFileName)
+		}
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cfg, err := parseConfig(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	settings := cfg.settings
+	if profile != "" {
+		overrides, ok := cfg.profiles[profile]
+		if !ok {
+			return fmt.Errorf("%s: no profile %q (profiles: %s)", path, profile, strings.Join(sortedNames(cfg.profiles), ", "))
+		}
+		settings = make(map[string]string, len(cfg.settings)+len(overrides))
+		for name, value := range cfg.settings {
+			settings[name] = value
+		}
+		for name, value := range overrides {
+			settings[name] = value
+		}
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, name := range sortedNames(settings) {
+		if name == "config" || name == "profile" || fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown setting %q", path, name)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, settings[name]); err != nil {
+			return fmt.Errorf("%s: %s: %v", path, name, err)
+		}
+	}
+	return nil
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseConfig reads a config file. It understands the subset of YAML that
+// flag settings need: mappings nested by indentation, plain and quoted
+// scalars, and lists written [a, b] or as "- item" lines, which become
+// comma-separated flag values.
+func parseConfig(r io.Reader) (*shapeConfig, error) {
+	lines, err := readYAMLLines(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, rest, err := parseYAMLMap(lines, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", rest[0].n)
+	}
+
+	cfg := &shapeConfig{settings: make(map[string]string), profiles: make(map[string]map[string]string)}
+	for key, value := range doc {
+		if key != "profiles" {
+			if cfg.settings[key], err = flagValue(key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		profiles, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profiles: want a mapping of profile names to settings")
+		}
+		for name, value := range profiles {
+			settings, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("profiles: %s: want a mapping of settings", name)
+			}
+			cfg.profiles[name] = make(map[string]string)
+			for key, value := range settings {
+				if cfg.profiles[name][key], err = flagValue(key, value); err != nil {
+					return nil, fmt.Errorf("profiles: %s: %v", name, err)
+				}
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// flagValue converts a setting into the value of a flag.
+func flagValue(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []string:
+		return strings.Join(v, ","), nil
+	}
+	return "", fmt.Errorf("%s: want a value or a list, not a mapping", key)
+}
+
+// yamlLine is a line of a config file without its comment and indentation.
+type yamlLine struct {
+	n      int // line number
+	indent int
+	text   string
+}
+
+func readYAMLLines(r io.Reader) ([]yamlLine, error) {
+	var lines []yamlLine
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(stripYAMLComment(scanner.Text()), " ")
+		text := strings.TrimLeft(line, " ")
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", n)
+		}
+		lines = append(lines, yamlLine{n: n, indent: len(line) - len(text), text: text})
+	}
+	return lines, scanner.Err()
+}
+
+// stripYAMLComment removes a "#" comment that starts the line or follows a
+// space, outside quotes.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseYAMLMap parses the mapping whose keys are indented like lines[0],
+// returning it with the lines after it.
+func parseYAMLMap(lines []yamlLine, indent int) (map[string]interface{}, []yamlLine, error) {
+	m := make(map[string]interface{})
+	for len(lines) > 0 && lines[0].indent == indent {
+		line := lines[0]
+		lines = lines[1:]
+		key, rest, ok := strings.Cut(line.text, ":")
+		if !ok || (rest != "" && rest[0] != ' ') {
+			return nil, nil, fmt.Errorf("line %d: want \"key: value\"", line.n)
+		}
+		key, rest = strings.TrimSpace(key), strings.TrimSpace(rest)
+		if _, dup := m[key]; dup {
+			return nil, nil, fmt.Errorf("line %d: %s is set twice", line.n, key)
+		}
+
+		var err error
+		switch {
+		case rest != "":
+			m[key], err = parseYAMLValue(rest)
+		case len(lines) > 0 && lines[0].indent >= indent && strings.HasPrefix(lines[0].text, "- "):
+			// The items may be indented like the key.
+			items, itemIndent := []string{}, lines[0].indent
+			for len(lines) > 0 && lines[0].indent == itemIndent && strings.HasPrefix(lines[0].text, "- ") {
+				item, err := parseYAMLScalar(strings.TrimSpace(lines[0].text[2:]))
+				if err != nil {
+					return nil, nil, fmt.Errorf("line %d: %v", lines[0].n, err)
+				}
+				items = append(items, item)
+				lines = lines[1:]
+			}
+			m[key] = items
+		case len(lines) > 0 && lines[0].indent > indent:
+			var child map[string]interface{}
+			if child, lines, err = parseYAMLMap(lines, lines[0].indent); err != nil {
+				return nil, nil, err
+			}
+			m[key] = child
+		default:
+			m[key] = ""
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", line.n, err)
+		}
+	}
+	if len(lines) > 0 && lines[0].indent > indent {
+		return nil, nil, fmt.Errorf("line %d: unexpected indentation", lines[0].n)
+	}
+	return m, lines, nil
+}
+
+// parseYAMLValue parses a scalar or a flow list such as [a, "b c"].
+func parseYAMLValue(s string) (interface{}, error) {
+	if !strings.HasPrefix(s, "[") {
+		return parseYAMLScalar(s)
+	}
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("unterminated list %s", s)
+	}
+	items := []string{}
+	for _, item := range strings.Split(s[1:len(s)-1], ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		value, err := parseYAMLScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+func parseYAMLScalar(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'"):
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	return s, nil
+}
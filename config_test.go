@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig(strings.NewReader(`# shared settings
+format: markdown
+exclude: [metadata, "**.debug"]   # noisy
+include: 'user.*'
+stats:
+
+profiles:
+  ci:
+    quiet: true
+    assert-required:
+    - id
+    - items[].id
+  docs:
+    describe: "true"
+    detect:
+      - country # two letters
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSettings := map[string]string{"format": "markdown", "exclude": "metadata,**.debug", "include": "user.*", "stats": ""}
+	if !reflect.DeepEqual(cfg.settings, wantSettings) {
+		t.Errorf("settings = %v, want %v", cfg.settings, wantSettings)
+	}
+	wantProfiles := map[string]map[string]string{
+		"ci":   {"quiet": "true", "assert-required": "id,items[].id"},
+		"docs": {"describe": "true", "detect": "country"},
+	}
+	if !reflect.DeepEqual(cfg.profiles, wantProfiles) {
+		t.Errorf("profiles = %v, want %v", cfg.profiles, wantProfiles)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"not a mapping", "format markdown\n", "line 1: want \"key: value\""},
+		{"bad indentation", "format: tree\n  stats: true\n", "line 2: unexpected indentation"},
+		{"duplicate", "format: tree\nformat: compact\n", "line 2: format is set twice"},
+		{"nested setting", "filters:\n  include: a\n", "filters: want a value or a list"},
+		{"bad profile", "profiles:\n  ci: true\n", "profiles: ci: want a mapping"},
+		{"unterminated", "include: \"user.*\n", "line 1: unterminated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFileName)
+	config := "format: compact\nstats: true\nprofiles:\n  docs:\n    format: markdown\n    budget: 100\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		profile string
+		want    string
+		wantErr string
+	}{
+		{"defaults", nil, "", "compact true 0", ""},
+		{"profile", nil, "docs", "markdown true 100", ""},
+		{"command line wins", []string{"--format=tree", "--stats=false"}, "docs", "tree false 100", ""},
+		{"unknown profile", nil, "ci", "", `no profile "ci" (profiles: docs)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			format := fs.String("format", "tree", "")
+			stats := fs.Bool("stats", false, "")
+			budget := fs.Int("budget", 0, "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyConfig(fs, path, tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("applyConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%s %v %d", *format, *stats, *budget); got != tt.want {
+				t.Errorf("flags = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("format", "tree", "")
+	if err := applyConfig(fs, path, ""); err == nil || !strings.Contains(err.Error(), `unknown setting "stats"`) {
+		t.Errorf("applyConfig() with an unknown setting: error = %v", err)
+	}
+}
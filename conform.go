@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// conformance is how well a dataset matches one candidate schema: the
+// documents that match it, the violations of each kind, and the share of
+// the schema's fields the documents hold.
+type conformance struct {
+	name     string
+	valid    int
+	kinds    map[string]int
+	coverage schemaCoverage
+}
+
+func (c conformance) violations() int {
+	n := 0
+	for _, count := range c.kinds {
+		n += count
+	}
+	return n
+}
+
+// conformanceMatrix validates docs against each schema, named by names, and
+// returns the results best match first: by documents matching, then fewest
+// violations, then most of the schema's fields seen. A schema the data
+// matches and covers fully is likely the version it was produced with.
+func conformanceMatrix(names []string, schemas []*jsonSchema, docs []interface{}) []conformance {
+	rows := make([]conformance, len(schemas))
+	for i, schema := range schemas {
+		v := newSchemaValidator(schema)
+		row := conformance{name: names[i], kinds: make(map[string]int)}
+		for _, doc := range docs {
+			violations := v.validate(doc)
+			if len(violations) == 0 {
+				row.valid++
+			}
+			for _, violation := range violations {
+				row.kinds[violation.kind]++
+			}
+		}
+		row.coverage = v.coverage()
+		rows[i] = row
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.valid != b.valid {
+			return a.valid > b.valid
+		}
+		if a.violations() != b.violations() {
+			return a.violations() < b.violations()
+		}
+		return a.coverage.fieldsSeen*b.coverage.fields > b.coverage.fieldsSeen*a.coverage.fields
+	})
+	return rows
+}
+
+// writeConformance prints the matrix as an aligned table with a row per
+// schema and a column per violation kind.
+func writeConformance(w io.Writer, rows []conformance, docs int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "schema\tvalid\t%s\tfields seen\n", strings.Join(violationKinds, "\t"))
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%d of %d", row.name, row.valid, docs)
+		for _, kind := range violationKinds {
+			fmt.Fprintf(tw, "\t%d", row.kinds[kind])
+		}
+		fmt.Fprintf(tw, "\t%d of %d\n", row.coverage.fieldsSeen, row.coverage.fields)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestConformanceMatrix(t *testing.T) {
+	var schemas []*jsonSchema
+	for _, s := range []string{
+		// v1 ids are numbers; v2 renamed status to state and added an enum.
+		`{"type": "object", "properties": {"id": {"type": "number"}, "status": {"type": "string"}}, "required": ["id", "status"]}`,
+		`{"type": "object", "properties": {"id": {"type": "string"}, "state": {"type": "string", "enum": ["a"]}}, "required": ["id", "state"]}`,
+		`{"type": "object", "properties": {"id": {"type": "number"}, "status": {"type": "string"}, "extra": {"type": "boolean"}}, "required": ["id"]}`,
+	} {
+		var schema jsonSchema
+		if err := json.Unmarshal([]byte(s), &schema); err != nil {
+			t.Fatal(err)
+		}
+		schemas = append(schemas, &schema)
+	}
+	var docs []interface{}
+	if err := json.Unmarshal([]byte(`[{"id": 1, "status": "open"}, {"id": 2, "status": "done"}]`), &docs); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := conformanceMatrix([]string{"v1", "v2", "v1-extra"}, schemas, docs)
+	var buf bytes.Buffer
+	if err := writeConformance(&buf, rows, len(docs)); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"schema    valid   missing  type  enum  range  fields seen\n" +
+		"v1        2 of 2  0        0     0     0      2 of 2\n" +
+		"v1-extra  2 of 2  0        0     0     0      2 of 3\n" +
+		"v2        0 of 2  2        2     0     0      1 of 2\n"
+	if buf.String() != want {
+		t.Errorf("matrix:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// csvDecoder reads a CSV or TSV file and returns its rows as a single
+// document, like sheetDecoder: an array of objects keyed by the header row,
+// in which empty cells are left out. Each column is typed as a whole, so
+// that a column holding "7" and "n/a" stays a string column.
+type csvDecoder struct {
+	r     io.Reader
+	comma rune // ',' for CSV, '\t' for TSV
+	order *keyOrder
+	done  bool
+}
+
+func newCSVDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return newDelimitedDecoder(r, opts, ',')
+}
+
+func newTSVDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return newDelimitedDecoder(r, opts, '\t')
+}
+
+func newDelimitedDecoder(r io.Reader, opts *decodeOptions, comma rune) valueDecoder {
+	d := &csvDecoder{r: r, comma: comma}
+	if opts != nil {
+		d.order = opts.keyOrder
+	}
+	return d
+}
+
+func (d *csvDecoder) Decode() (interface{}, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+	d.done = true
+
+	var records [][]string
+	var err error
+	if d.comma == '\t' {
+		records, err = readTSV(d.r)
+	} else {
+		cr := csv.NewReader(d.r)
+		cr.FieldsPerRecord = -1
+		records, err = cr.ReadAll()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && len(records[0]) > 0 {
+		// Spreadsheet programs often write a byte order mark.
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+	return rowsToObjects(typeColumns(records), d.order), nil
+}
+
+// readTSV splits r into lines and the lines into tab-separated values. TSV
+// has no quoting, so unlike in CSV, quotes are part of the values.
+func readTSV(r io.Reader) ([][]string, error) {
+	var records [][]string
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			line = strings.TrimRight(line, "\r\n")
+			records = append(records, strings.Split(line, "\t"))
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// csvNumber matches the numbers of a number column. Integers with leading
+// zeros, such as postal codes, are not numbers.
+var csvNumber = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$`)
+
+// Column types inferred by typeColumns. csvEmpty is the type of empty
+// values, and of columns that only hold empty values.
+const (
+	csvEmpty = iota
+	csvBoolean
+	csvNumeric
+	csvString
+)
+
+// typeColumns converts records into spreadsheet rows: empty cells become nil
+// and the cells below the header become booleans when every value in their
+// column is true or false, numbers when every value is a number, and
+// strings otherwise. Dates stay strings.
+func typeColumns(records [][]string) [][]interface{} {
+	header := -1
+	var kinds []int
+	for i, record := range records {
+		if header < 0 {
+			if !isBlankRecord(record) {
+				header = i
+			}
+			continue
+		}
+		for col, value := range record {
+			for len(kinds) <= col {
+				kinds = append(kinds, csvEmpty)
+			}
+			switch kind := csvKind(value); {
+			case kinds[col] == csvEmpty:
+				kinds[col] = kind
+			case kind != csvEmpty && kind != kinds[col]:
+				kinds[col] = csvString
+			}
+		}
+	}
+
+	rows := make([][]interface{}, len(records))
+	for i, record := range records {
+		row := make([]interface{}, len(record))
+		for col, value := range record {
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			if i <= header {
+				row[col] = value
+				continue
+			}
+			row[col] = csvValue(value, kinds[col])
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// csvKind returns the column type value fits best.
+func csvKind(value string) int {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return csvEmpty
+	case strings.EqualFold(value, "true") || strings.EqualFold(value, "false"):
+		return csvBoolean
+	case csvNumber.MatchString(value):
+		return csvNumeric
+	}
+	return csvString
+}
+
+func csvValue(value string, kind int) interface{} {
+	switch kind {
+	case csvBoolean:
+		return strings.EqualFold(strings.TrimSpace(value), "true")
+	case csvNumeric:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return f
+	}
+	return value
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVDecoder(t *testing.T) {
+	input := "\ufeffid,zip,active,score,note,when\n" +
+		"1,02139,true,1.5,hello,2024-01-05\n" +
+		"2,10001,FALSE,,\"a, b\",2024-02-01\n" +
+		"3,94110,,-2e3,7,\n"
+	docs, err := decodeAll(newCSVDecoder(strings.NewReader(input), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{[]interface{}{
+		map[string]interface{}{"id": 1.0, "zip": "02139", "active": true, "score": 1.5, "note": "hello", "when": "2024-01-05"},
+		map[string]interface{}{"id": 2.0, "zip": "10001", "active": false, "note": "a, b", "when": "2024-02-01"},
+		map[string]interface{}{"id": 3.0, "zip": "94110", "score": -2000.0, "note": "7"},
+	}}
+	if !reflect.DeepEqual(docs, expected) {
+		t.Errorf("decodeAll() = %#v; want %#v", docs, expected)
+	}
+
+	fields := analyzeJSON(docs[0])
+	if !fields["score"].Optional || fields["id"].Optional || fields["zip"].Type != "string" {
+		t.Errorf("unexpected shape: score %+v, id %+v, zip %+v", fields["score"], fields["id"], fields["zip"])
+	}
+	for key, want := range map[string]string{"id": "[integer, ", "score": "[float, ", "when": "[date, ", "note": "[length "} {
+		if got := fields[key].stats.String(); !strings.HasPrefix(got, want) {
+			t.Errorf("%s stats = %q; want prefix %q", key, got, want)
+		}
+	}
+}
+
+func TestTSVDecoder(t *testing.T) {
+	input := "name\tquote\n" + "Ada\t\"so it goes\n" + "\n" + "Bob\t\t1\n"
+	order := newKeyOrder()
+	docs, err := decodeAll(newTSVDecoder(strings.NewReader(input), &decodeOptions{keyOrder: order}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{[]interface{}{
+		map[string]interface{}{"name": "Ada", "quote": `"so it goes`},
+		map[string]interface{}{"name": "Bob", "column3": 1.0},
+	}}
+	if !reflect.DeepEqual(docs, expected) {
+		t.Errorf("decodeAll() = %#v; want %#v", docs, expected)
+	}
+	if want := map[string]int{"name": 0, "quote": 1, "column3": 2}; !reflect.DeepEqual(order.positions[""], want) {
+		t.Errorf("column order = %v; want %v", order.positions[""], want)
+	}
+}
+
+func TestCSVKind(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", csvEmpty},
+		{" ", csvEmpty},
+		{"True", csvBoolean},
+		{"0", csvNumeric},
+		{"-12.5", csvNumeric},
+		{"1e9", csvNumeric},
+		{"007", csvString},
+		{"1,000", csvString},
+		{"NaN", csvString},
+		{"2024-01-05", csvString},
+	}
+	for _, tt := range tests {
+		if got := csvKind(tt.value); got != tt.want {
+			t.Errorf("csvKind(%q) = %d; want %d", tt.value, got, tt.want)
+		}
+	}
+}
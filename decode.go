@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+)
+
+// valueDecoder decodes successive documents from an input into the generic
+// form analyzeJSON works on: map[string]interface{}, []interface{}, float64,
+// string, bool and nil. Decode returns io.EOF once the input is exhausted.
+type valueDecoder interface {
+	Decode() (interface{}, error)
+}
+
+// decodeOptions holds the settings that apply to particular input encodings.
+type decodeOptions struct {
+	sheet string // spreadsheet sheet name or 1-based index
+
+	protoDescriptor string // path of a protobuf FileDescriptorSet
+	protoMessage    string // fully qualified record message type
+	protoSingle     bool   // input is one message, not length-delimited records
+
+	skipInvalid bool // read JSON as newline-delimited records and skip bad ones
+
+	keyOrder *keyOrder // records key order for --sort=original, when set
+
+	// maxInputBytes and maxDocs bound the bytes read from all sources and
+	// the documents decoded from them, when positive.
+	maxInputBytes int64
+	maxDocs       int
+
+	transform *docTransform // preprocesses each document, when set
+}
+
+// decoders maps each --input value to the constructor of its decoder.
+var decoders = map[string]func(io.Reader, *decodeOptions) valueDecoder{
+	"json":      newJSONDecoder,
+	"msgpack":   newMsgpackDecoder,
+	"cbor":      newCBORDecoder,
+	"bson":      newBSONDecoder,
+	"csv":       newCSVDecoder,
+	"tsv":       newTSVDecoder,
+	"xlsx":      newXLSXDecoder,
+	"ods":       newODSDecoder,
+	"firestore": newFirestoreBundleDecoder,
+	"protobuf":  newProtoDecoder,
+	"journal":   newJournalDecoder,
+	"docker":    newDockerLogDecoder,
+}
+
+// inputForName guesses the input encoding from a file name's extension,
+// defaulting to JSON. URL query strings are ignored.
+func inputForName(name string) string {
+	name, _, _ = strings.Cut(name, "?")
+	if strings.HasSuffix(name, "-json.log") {
+		// Docker's json-file driver writes <container-id>-json.log.
+		return "docker"
+	}
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".msgpack", ".mpk":
+		return "msgpack"
+	case ".cbor", ".bson", ".csv", ".tsv", ".xlsx", ".ods":
+		return ext[1:]
+	case ".tab":
+		return "tsv"
+	}
+	return "json"
+}
+
+func inputNames() string {
+	names := make([]string, 0, len(decoders))
+	for name := range decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// decodeAll reads every document from dec, so that back-to-back documents
+// such as "{}{}{}" are all analyzed rather than just the first.
+func decodeAll(dec valueDecoder) ([]interface{}, error) {
+	return decodeAllSkipping(dec, nil)
+}
+
+// decodeAllSkipping is decodeAll, except that when onInvalid is not nil,
+// invalid records the decoder can continue past are passed to it instead of
+// ending the input.
+func decodeAllSkipping(dec valueDecoder, onInvalid func(error)) ([]interface{}, error) {
+	var docs []interface{}
+	err := decodeEach(dec, onInvalid, func(doc interface{}) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// decodeEach is decodeAllSkipping, except that each document is passed to
+// each as it is decoded rather than collected. An error from each ends the
+// input.
+func decodeEach(dec valueDecoder, onInvalid func(error), each func(doc interface{}) error) error {
+	for n := 1; ; {
+		doc, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		var derr *decodeError
+		if onInvalid != nil && errors.As(err, &derr) && derr.recoverable {
+			onInvalid(err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("document %d: %w", n, err)
+		}
+		if err := each(doc); err != nil {
+			return err
+		}
+		n++
+	}
+}
+
+// floatValue keeps finite numbers and spells out the others as strings, as
+// the protobuf JSON mapping does: JSON has no NaN or infinity, which binary
+// encodings can hold.
+func floatValue(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
+
+// decodeError locates a decoding failure in the input.
+type decodeError struct {
+	offset       int64 // of the offending byte
+	line, column int   // 1-based; zero when unknown
+	snippet      string
+	err          error
+	recoverable  bool // the decoder can go on with the next record
+}
+
+func (e *decodeError) Error() string {
+	msg := fmt.Sprintf("byte %d: %v", e.offset, e.err)
+	if e.line > 0 {
+		msg = fmt.Sprintf("line %d, column %d (byte %d): %v", e.line, e.column, e.offset, e.err)
+	}
+	if e.snippet != "" {
+		msg += fmt.Sprintf(" near %q", e.snippet)
+	}
+	return msg
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// Limits of the input kept by trackingReader to locate errors.
+const (
+	trackingWindow = 1 << 20
+	snippetRadius  = 30
+)
+
+// trackingReader counts the bytes and lines read through it and keeps the
+// most recent ones, so that an error offset can be turned into a line,
+// column and snippet.
+type trackingReader struct {
+	r      io.Reader
+	n      int64  // bytes read
+	window []byte // the bytes before n
+	start  int64  // offset of window[0]
+	lines  int    // newlines before start
+}
+
+func (t *trackingReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	t.n += int64(n)
+	t.window = append(t.window, p[:n]...)
+	if len(t.window) > 2*trackingWindow {
+		drop := len(t.window) - trackingWindow
+		t.lines += bytes.Count(t.window[:drop], []byte{'\n'})
+		t.start += int64(drop)
+		t.window = append(t.window[:0], t.window[drop:]...)
+	}
+	return n, err
+}
+
+// locate wraps err with the position of offset, adding the line, column
+// and snippet when the offset is still in the window.
+func (t *trackingReader) locate(offset int64, err error) *decodeError {
+	e := &decodeError{offset: offset, err: err}
+	if offset < t.start || offset > t.start+int64(len(t.window)) {
+		return e
+	}
+
+	i := int(offset - t.start)
+	lineStart := bytes.LastIndexByte(t.window[:i], '\n') + 1
+	if lineStart > 0 || t.start == 0 {
+		// Otherwise the line began before the window and its column is
+		// unknown.
+		e.line = t.lines + bytes.Count(t.window[:i], []byte{'\n'}) + 1
+		e.column = i - lineStart + 1
+	}
+
+	lineEnd := len(t.window)
+	if j := bytes.IndexByte(t.window[i:], '\n'); j >= 0 {
+		lineEnd = i + j
+	}
+	from, to := max(lineStart, i-snippetRadius), min(lineEnd, i+snippetRadius)
+	e.snippet = strings.TrimSpace(string(t.window[from:to]))
+	return e
+}
+
+// jsonDecoder decodes a stream of JSON documents. With skipInvalid it reads
+// newline-delimited records instead, so that an invalid line can be
+// skipped; input whose first record spans lines is read as a stream.
+type jsonDecoder struct {
+	src   *trackingReader
+	dec   *json.Decoder // stream mode
+	base  int64         // offset in src where dec's input starts
+	lines *bufio.Reader // line mode
+	queue []interface{}
+	seen  bool
+	order *keyOrder
+}
+
+func newJSONDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	d := &jsonDecoder{src: &trackingReader{r: r}}
+	if opts != nil {
+		d.order = opts.keyOrder
+	}
+	if opts != nil && opts.skipInvalid {
+		d.lines = bufio.NewReader(d.src)
+	} else {
+		d.dec = json.NewDecoder(d.src)
+	}
+	return d
+}
+
+func (d *jsonDecoder) Decode() (interface{}, error) {
+	if d.dec != nil {
+		return d.decodeStream()
+	}
+
+	for len(d.queue) == 0 {
+		lineStart := d.src.n - int64(d.lines.Buffered())
+		line, err := d.lines.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(line))
+		for {
+			v, derr := d.decodeValue(dec)
+			if derr == io.EOF {
+				break
+			}
+			if derr == io.ErrUnexpectedEOF && !d.seen && len(d.queue) == 0 {
+				// A multi-line document: read the rest as a stream.
+				d.dec = json.NewDecoder(io.MultiReader(bytes.NewReader(line), d.lines))
+				d.base = lineStart
+				return d.decodeStream()
+			}
+			if derr != nil {
+				d.queue = nil
+				end := int64(len(bytes.TrimRight(line, "\r\n")))
+				e := d.src.locate(lineStart+errorOffset(derr, end), derr)
+				e.recoverable = true
+				return nil, e
+			}
+			d.queue = append(d.queue, v)
+		}
+	}
+
+	d.seen = true
+	v := d.queue[0]
+	d.queue = d.queue[1:]
+	return v, nil
+}
+
+func (d *jsonDecoder) decodeStream() (interface{}, error) {
+	v, err := d.decodeValue(d.dec)
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		var syntax *json.SyntaxError
+		if !errors.As(err, &syntax) {
+			// The input ran out mid-document.
+			return nil, d.src.locate(d.src.n, err)
+		}
+		return nil, d.src.locate(d.base+errorOffset(err, 0), err)
+	}
+	return v, nil
+}
+
+// decodeValue decodes the next document from dec, recording the order of its
+// keys when d.order is set.
+func (d *jsonDecoder) decodeValue(dec *json.Decoder) (interface{}, error) {
+	var v interface{}
+	if d.order == nil {
+		err := dec.Decode(&v)
+		return v, err
+	}
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return v, d.order.scan(raw)
+}
+
+// errorOffset returns the offset of the byte a JSON error refers to,
+// falling back to end, the offset reached when the input ran out.
+func errorOffset(err error, end int64) int64 {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) && syntax.Offset > 0 {
+		return syntax.Offset - 1
+	}
+	return end
+}
+
+// maxDecodeDepth bounds the nesting of binary documents so that malformed
+// input cannot exhaust the stack.
+const maxDecodeDepth = 10000
+
+// readBytes reads exactly n bytes from r. Large lengths come from untrusted
+// headers, so the buffer grows with the data actually read rather than being
+// allocated up front, and a length past what the input could still hold
+// fails at its end.
+func readBytes(r io.Reader, n uint64) ([]byte, error) {
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("length %d is longer than any input", n)
+	}
+	if n <= 1<<16 {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(r, buf)
+		return buf, unexpectedEOF(err)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// unexpectedEOF converts io.EOF in the middle of a document into
+// io.ErrUnexpectedEOF, so that only a clean end of input reads as io.EOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// capacity returns a safe initial capacity for a container whose length was
+// read from the input.
+func capacity(n uint64) int {
+	if n > 1024 {
+		return 1024
+	}
+	return int(n)
+}
+
+// mapKey converts a non-string map key from a binary format into a string.
+func mapKey(k interface{}) string {
+	switch v := k.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func newBufferedReader(r io.Reader) *bufio.Reader {
+	if br, ok := r.(*bufio.Reader); ok {
+		return br
+	}
+	return bufio.NewReader(r)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAll(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []interface{}
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{`{"a":1}`, []interface{}{map[string]interface{}{"a": 1.0}}, false},
+		{`{"a":1}{"b":2}[3]`, []interface{}{
+			map[string]interface{}{"a": 1.0},
+			map[string]interface{}{"b": 2.0},
+			[]interface{}{3.0},
+		}, false},
+		{"{\"a\":1}\n\n  {\"a\":2}\n", []interface{}{
+			map[string]interface{}{"a": 1.0},
+			map[string]interface{}{"a": 2.0},
+		}, false},
+		{`{"a":1}{"a":`, nil, true},
+	}
+
+	for _, tt := range tests {
+		result, err := decodeAll(newJSONDecoder(strings.NewReader(tt.input), nil))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("decodeAll(%q) error = %v; wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("decodeAll(%q) = %#v; want %#v", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestJSONDecoderErrorLocation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{\"a\": 1,\n \"b\": tru,\n}", `line 2, column 10 (byte 18): invalid character ',' in literal true (expecting 'e') near "\"b\": tru,"`},
+		{"{\"a\": [1,\n 2", `line 2, column 3 (byte 12): unexpected EOF near "2"`},
+		{"{}\n{} x", `line 2, column 4 (byte 6): invalid character 'x' looking for beginning of value near "{} x"`},
+	}
+
+	for _, tt := range tests {
+		_, err := decodeAll(newJSONDecoder(strings.NewReader(tt.input), nil))
+		if err == nil || !strings.HasSuffix(err.Error(), tt.expected) {
+			t.Errorf("decodeAll(%q) error = %v; want it to end with %s", tt.input, err, tt.expected)
+		}
+	}
+}
+
+func TestJSONDecoderSkipInvalid(t *testing.T) {
+	input := "{\"a\": 1}\n{\"a\": \n\n{\"a\": 2}{\"a\": 3}\n[1, x]\n{\"a\": 4}"
+	var skipped []string
+	docs, err := decodeAllSkipping(newJSONDecoder(strings.NewReader(input), &decodeOptions{skipInvalid: true}), func(err error) {
+		skipped = append(skipped, err.Error())
+	})
+	if err != nil {
+		t.Fatalf("decodeAllSkipping() error: %v", err)
+	}
+	if len(docs) != 4 {
+		t.Errorf("expected 4 valid records, got %#v", docs)
+	}
+	if len(skipped) != 2 || !strings.HasPrefix(skipped[0], "line 2, column 7") || !strings.HasPrefix(skipped[1], "line 5, column 5") {
+		t.Errorf("unexpected skipped records: %q", skipped)
+	}
+
+	// A document spanning lines is still read whole.
+	docs, err = decodeAllSkipping(newJSONDecoder(strings.NewReader("{\n  \"a\": 1\n}\n{\"a\": 2}"), &decodeOptions{skipInvalid: true}), func(error) {})
+	if err != nil || len(docs) != 2 {
+		t.Errorf("multi-line document: got %#v, %v", docs, err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed samples/*.json
+var samples embed.FS
+
+// demoStep is a step of the walkthrough. It renders the shape of a sample
+// in a format or, given run, runs a command on the sample.
+type demoStep struct {
+	title   string
+	sample  string
+	format  string
+	command string // shown for a step with run
+	run     func(out io.Writer, sample string, data interface{}) error
+	note    string
+}
+
+var demoSteps = []demoStep{
+	{
+		title:  "Analyze an API response",
+		sample: "api_response",
+		format: "tree",
+		note: "Every user object in \"data\" is merged into one shape. Fields missing\n" +
+			"for some users (last_login, profile.bio) are marked (optional), and fields\n" +
+			"seen as null (profile.avatar) are marked (nullable).",
+	},
+	{
+		title:  "Analyze an event stream",
+		sample: "event_stream",
+		format: "tree",
+		note: "Events of different kinds share one shape. Properties that only some events\n" +
+			"carry show up as optional, and nested arrays of objects (properties.items)\n" +
+			"are merged like top-level ones.",
+	},
+	{
+		title:  "Analyze a config file",
+		sample: "config",
+		format: "tree",
+		note: "A single document has nothing to compare against, so its fields are\n" +
+			"marked (assumed required) rather than required outright; see\n" +
+			"--assume-unknown. Null values still mark a field (nullable).",
+	},
+	{
+		title:   "Validate new documents against a schema",
+		sample:  "event_stream_v2",
+		command: "json-shape demo event_stream | json-shape --format=jsonschema > events.schema.json\n$ json-shape demo event_stream_v2 | json-shape validate --schema events.schema.json",
+		run:     demoValidate,
+		note: "A schema written from one batch of events checks the next. These events\n" +
+			"send ts as a string and one lacks a session, so validate reports them and\n" +
+			"exits with status 3.",
+	},
+	{
+		title:   "Detect drift from a saved shape",
+		sample:  "event_stream_v2",
+		command: "json-shape demo event_stream | json-shape --format=compact > events.shape\n$ json-shape demo event_stream_v2 | json-shape --format=badge --baseline=events.shape",
+		run:     demoDrift,
+		note: "A shape saved with --format=compact is a baseline: the badge formats count\n" +
+			"the fields added, removed or changed since, here ts, session and\n" +
+			"context.locale among them.",
+	},
+	{
+		title:   "Generate test data",
+		sample:  "event_stream",
+		command: "json-shape demo event_stream | json-shape generate --count=3",
+		run:     demoGenerate,
+		note: "generate writes fake documents shaped and distributed like the input,\n" +
+			"without copying its values, for tests and fixtures.",
+	},
+	{
+		title:  "Generate an Avro schema",
+		sample: "event_stream",
+		format: "avro",
+		note:   "The same shape can be generated as a schema. Optional and nullable fields\nbecome nullable unions.",
+	},
+	{
+		title:  "Generate a Parquet schema",
+		sample: "event_stream",
+		format: "parquet",
+		note:   "Or as a Parquet message schema, ready for columnar ingestion.",
+	},
+}
+
+// runDemo walks through demoSteps, pausing for Enter between steps. Entering
+// "q" stops the walkthrough; when in is exhausted the remaining steps run
+// without pausing.
+func runDemo(in io.Reader, out io.Writer) error {
+	prompt := bufio.NewReader(in)
+	interactive := true
+
+	fmt.Fprintln(out, "json-shape demo: a tour using bundled sample data.")
+	fmt.Fprintf(out, "Run `json-shape demo <sample>` to print a sample (%s).\n", strings.Join(sampleNames(), ", "))
+
+	for i, step := range demoSteps {
+		fmt.Fprintf(out, "\n== Step %d/%d: %s ==\n\n", i+1, len(demoSteps), step.title)
+		fmt.Fprintln(out, step.note)
+
+		data, err := loadSample(step.sample)
+		if err != nil {
+			return err
+		}
+		if step.run != nil {
+			fmt.Fprintf(out, "\n$ %s\n", step.command)
+			if err := step.run(out, step.sample, data); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(out, "\n$ json-shape demo %s | json-shape --format=%s\n", step.sample, step.format)
+			if err := renderSample(out, data, step.format, &renderOptions{}); err != nil {
+				return err
+			}
+		}
+
+		if interactive && i < len(demoSteps)-1 {
+			fmt.Fprint(out, "\nPress Enter to continue, or q to quit: ")
+			line, err := prompt.ReadString('\n')
+			if err != nil {
+				interactive = false
+				fmt.Fprintln(out)
+			} else if strings.TrimSpace(line) == "q" {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func loadSample(name string) (interface{}, error) {
+	data, err := samples.ReadFile(path.Join("samples", name+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var jsonData interface{}
+	if err := json.Unmarshal(data, &jsonData); err != nil {
+		return nil, fmt.Errorf("sample %s: %v", name, err)
+	}
+	return jsonData, nil
+}
+
+// renderSample writes the shape of a sample in format, followed by any
+// warnings.
+func renderSample(out io.Writer, data interface{}, format string, opts *renderOptions) error {
+	fields := analyzeJSON(data)
+	if _, single := data.(map[string]interface{}); single {
+		assumeOptionality(fields, assumeRequired)
+	}
+	if err := formats[format](out, fields, opts); err != nil {
+		return err
+	}
+	for _, warning := range opts.warnings {
+		fmt.Fprintf(out, "warning: %s: %s\n", format, warning)
+	}
+	return nil
+}
+
+// demoValidate validates the documents of sample against the JSON Schema of
+// the event_stream sample, as runValidate does.
+func demoValidate(out io.Writer, sample string, _ interface{}) error {
+	events, err := loadSample("event_stream")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := renderSample(&buf, events, "jsonschema", &renderOptions{}); err != nil {
+		return err
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		return err
+	}
+	src := source{name: "-", open: func() (io.ReadCloser, error) {
+		return samples.Open(path.Join("samples", sample+".json"))
+	}}
+	docs, invalid, err := validateReport(out, []source{src}, "json", &decodeOptions{}, newSchemaValidator(&schema), out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d documents, %d invalid\n", docs, invalid)
+	return nil
+}
+
+// demoDrift writes the badge of data with the shape of the event_stream
+// sample as its baseline.
+func demoDrift(out io.Writer, _ string, data interface{}) error {
+	events, err := loadSample("event_stream")
+	if err != nil {
+		return err
+	}
+	baseline := &shapeBaseline{lines: shapeLines(analyzeJSON(events))}
+	return renderSample(out, data, "badge", &renderOptions{baseline: baseline})
+}
+
+// demoGenerate writes three documents replicating data.
+func demoGenerate(out io.Writer, _ string, data interface{}) error {
+	a := newAnalyzer()
+	a.add(data)
+	fields := a.shape()
+	g := newReplicator(1, false)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	for range 3 {
+		if err := enc.Encode(g.object(fields, a.objects)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printSample writes the raw contents of a bundled sample to out.
+func printSample(out io.Writer, name string) error {
+	data, err := samples.ReadFile(path.Join("samples", name+".json"))
+	if err != nil {
+		return fmt.Errorf("unknown sample %q (want one of: %s)", name, strings.Join(sampleNames(), ", "))
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func sampleNames() []string {
+	entries, _ := samples.ReadDir("samples")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunDemo(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runDemo(strings.NewReader(""), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	output := buf.String()
+	for i, step := range demoSteps {
+		if !strings.Contains(output, step.title) {
+			t.Errorf("output missing step %d title %q", i+1, step.title)
+		}
+	}
+	if !strings.Contains(output, "service: string (assumed required)") {
+		t.Error("output missing assumed optionality of the config sample")
+	}
+	if !strings.Contains(output, "message root {") {
+		t.Error("output missing generated Parquet schema")
+	}
+	for _, want := range []string{"document 2 at byte 110\tsession\tmissing", "2 documents, 2 invalid", "drifted (", `{"context":{`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestRunDemoQuit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runDemo(strings.NewReader("q\n"), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), demoSteps[1].title) {
+		t.Error("demo should stop after entering q")
+	}
+}
+
+func TestPrintSample(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printSample(&buf, "config"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"service": "billing"`) {
+		t.Errorf("unexpected sample contents:\n%s", buf.String())
+	}
+
+	if err := printSample(&buf, "missing"); err == nil {
+		t.Error("expected error for unknown sample")
+	}
+}
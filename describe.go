@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// stringFormat is a recognizable kind of string value, named the way a
+// field description would put it.
+type stringFormat struct {
+	id      string // selects the format with --detect
+	name    string
+	letters bool // values are letters only, so lengths are counted in letters
+	pattern *regexp.Regexp
+}
+
+// stringFormats are the formats --describe recognizes, most specific first.
+// fieldStats records which of them every string of a field matches.
+var stringFormats = []stringFormat{
+	{"timestamp", "ISO 8601 timestamp", false, regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?$`)},
+	{"date", "ISO 8601 date", false, regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)},
+	{"uuid", "UUID", false, regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)},
+	{"email", "email address", false, regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)},
+	{"url", "URL", false, regexp.MustCompile(`^https?://\S+$`)},
+	{"ipv4", "IPv4 address", false, regexp.MustCompile(`^(\d{1,3}\.){3}\d{1,3}$`)},
+	{"color", "hex color", false, regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)},
+	{"country", "ISO country code", true, regexp.MustCompile(`^[A-Z]{2}$`)},
+	{"currency", "ISO currency code", true, regexp.MustCompile(`^[A-Z]{3}$`)},
+	{"numeric", "numeric string", false, regexp.MustCompile(`^-?\d+(\.\d+)?$`)},
+}
+
+// parseDetectors parses the comma-separated format ids of --detect into the
+// set of stringFormats indexes to leave out of descriptions. An empty list
+// enables every format and "none" disables them all.
+func parseDetectors(list string) (uint, error) {
+	if strings.TrimSpace(list) == "" {
+		return 0, nil
+	}
+	skip := allFormats
+	if strings.TrimSpace(list) == "none" {
+		return skip, nil
+	}
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		i := slices.IndexFunc(stringFormats, func(f stringFormat) bool { return f.id == id })
+		if i < 0 {
+			return 0, fmt.Errorf("unknown string format %q (want one of: %s)", id, detectorNames())
+		}
+		skip &^= 1 << i
+	}
+	return skip, nil
+}
+
+func detectorNames() string {
+	ids := make([]string, len(stringFormats))
+	for i, f := range stringFormats {
+		ids[i] = f.id
+	}
+	return strings.Join(ids, ", ")
+}
+
+// allFormats is the set of every stringFormats index.
+var allFormats = uint(1)<<len(stringFormats) - 1
+
+// dateFormats is the set of the stringFormats indexes of dates and
+// timestamps.
+var dateFormats = formatSet("timestamp", "date")
+
+func formatSet(ids ...string) uint {
+	var set uint
+	for i, f := range stringFormats {
+		if slices.Contains(ids, f.id) {
+			set |= 1 << i
+		}
+	}
+	return set
+}
+
+// formatMisfits returns misfits, a set of stringFormats indexes, with the
+// formats v does not match added. Most fields hold free text that soon
+// misfits every format, after which their values are not matched at all.
+func formatMisfits(v string, misfits uint) uint {
+	for i, f := range stringFormats {
+		if misfits == allFormats {
+			break
+		}
+		if misfits&(1<<i) == 0 && !f.pattern.MatchString(v) {
+			misfits |= 1 << i
+		}
+	}
+	return misfits
+}
+
+// describeField drafts a description of field from the values seen, such as
+// "ISO country code, 2 letters, 14 distinct values", as a starting point
+// for documentation. It returns "" for objects, which their fields describe.
+// Formats in opts.skipFormats are not named.
+func describeField(field *FieldInfo, opts *renderOptions) string {
+	s := &field.stats
+	typ := field.Type
+	if strings.HasPrefix(typ, "array<") || (typ == "" && field.isArray) {
+		typ = "array"
+	}
+
+	var parts []string
+	switch typ {
+	case "string":
+		parts = describeStrings(s, opts.skipFormats)
+	case "number":
+		if s.numbers > 0 {
+			kind := "integer"
+			if s.fractions {
+				kind = "number"
+			}
+			switch {
+			case s.min == s.max && s.numbers > 1:
+				parts = append(parts, "always "+formatNumber(s.min))
+			case s.min == s.max:
+				parts = append(parts, kind)
+			default:
+				parts = append(parts, fmt.Sprintf("%s from %s to %s", kind, formatNumber(s.min), formatNumber(s.max)))
+			}
+		}
+	case "boolean":
+		if s.booleans > 0 {
+			parts = append(parts, fmt.Sprintf("flag, true in %d of %d", s.trues, s.booleans))
+		}
+	case "array":
+		if s.arrays > 0 {
+			switch {
+			case s.minItems == 1 && s.maxItems == 1:
+				parts = append(parts, "list of 1 item")
+			case s.minItems == s.maxItems:
+				parts = append(parts, fmt.Sprintf("list of %d items", s.minItems))
+			default:
+				parts = append(parts, fmt.Sprintf("list of %d-%d items", s.minItems, s.maxItems))
+			}
+		}
+	case "unknown":
+		parts = append(parts, "always null")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeStrings(s *fieldStats, skipFormats uint) []string {
+	if s.strings == 0 {
+		return nil
+	}
+	var parts []string
+	unit := "character"
+	for i, f := range stringFormats {
+		if (s.misfits|skipFormats)&(1<<i) == 0 {
+			parts = append(parts, f.name)
+			if f.letters {
+				unit = "letter"
+			}
+			break
+		}
+	}
+	switch {
+	case s.maxLen == 1 && s.minLen == 1:
+		parts = append(parts, "1 "+unit)
+	case s.minLen == s.maxLen:
+		parts = append(parts, fmt.Sprintf("%d %ss", s.minLen, unit))
+	default:
+		parts = append(parts, fmt.Sprintf("%d-%d %ss", s.minLen, s.maxLen, unit))
+	}
+
+	switch values := s.enumValues(); {
+	case len(values) == 1:
+		return append(parts, "always "+jsString(values[0]))
+	case len(values) > 1:
+		return append(parts, "one of "+strings.Join(values, ", "))
+	}
+	switch n, exact := s.distinct.count(); {
+	case n == 1:
+		parts = append(parts, "1 distinct value")
+	case exact:
+		parts = append(parts, fmt.Sprintf("%d distinct values", n))
+	default:
+		parts = append(parts, fmt.Sprintf("~%d distinct values", n))
+	}
+	return parts
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDescribeField(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   string
+	}{
+		{"country codes", []interface{}{"US", "DE", "US", "FR", "DE", "FR"}, "ISO country code, 2 letters, one of DE, FR, US"},
+		{"constant", []interface{}{"v1", "v1"}, "2 characters, always \"v1\""},
+		{"single character", []interface{}{"x"}, "1 character, 1 distinct value"},
+		{"uuids", []interface{}{"3f2b8c1e-1a2b-4c3d-8e9f-0a1b2c3d4e5f", "3f2b8c1e-1a2b-4c3d-8e9f-0a1b2c3d4e5a"}, "UUID, 36 characters, 2 distinct values"},
+		{"timestamps", []interface{}{"2024-01-02T03:04:05Z", "2024-01-02 03:04:05.5+02:00"}, "ISO 8601 timestamp, 20-27 characters, 2 distinct values"},
+		{"mixed formats", []interface{}{"2024-01-02", "soon"}, "4-10 characters, 2 distinct values"},
+		{"integers", []interface{}{18.0, 64.0, 30.0}, "integer from 18 to 64"},
+		{"fractions", []interface{}{0.5, 2.0}, "number from 0.5 to 2"},
+		{"fixed number", []interface{}{3.0, 3.0}, "always 3"},
+		{"booleans", []interface{}{true, false, true}, "flag, true in 2 of 3"},
+		{"arrays", []interface{}{[]interface{}{1.0}, []interface{}{}}, "list of 0-1 items"},
+		{"nulls", []interface{}{nil, nil}, "always null"},
+		{"objects", []interface{}{map[string]interface{}{"a": 1.0}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs []interface{}
+			for _, v := range tt.values {
+				docs = append(docs, map[string]interface{}{"f": v})
+			}
+			if got := describeField(analyzeJSON(docs)["f"], &renderOptions{}); got != tt.want {
+				t.Errorf("describeField() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatMisfits(t *testing.T) {
+	date := slices.IndexFunc(stringFormats, func(f stringFormat) bool { return f.id == "date" })
+	tests := []struct {
+		values []string
+		want   uint
+	}{
+		{[]string{"2024-01-02"}, allFormats &^ (1 << date)},
+		{[]string{"2024-01-02", "2024-01-03"}, allFormats &^ (1 << date)},
+		{[]string{"2024-01-02", "free text"}, allFormats},
+		// A format once ruled out stays so.
+		{[]string{"free text", "2024-01-02"}, allFormats},
+	}
+	for _, tt := range tests {
+		var misfits uint
+		for _, v := range tt.values {
+			misfits = formatMisfits(v, misfits)
+		}
+		if misfits != tt.want {
+			t.Errorf("formatMisfits(%q) = %b, want %b", tt.values, misfits, tt.want)
+		}
+	}
+}
+
+func TestWriteMarkdownDescribe(t *testing.T) {
+	fields := analyzeJSON([]interface{}{
+		map[string]interface{}{"age": 30.0, "address": map[string]interface{}{"city": "Oslo"}},
+	})
+
+	var buf bytes.Buffer
+	if err := writeMarkdown(&buf, fields, &renderOptions{describe: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"| `address` | `object` | yes | no | TODO |  |",
+		"| `address.city` | `string` | yes | no | 4 characters, 1 distinct value | `\"Oslo\"` |",
+		"| `age` | `number` | yes | no | integer | `30` |",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("output missing expected line: %q\nGot:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+)
+
+// typeDecision is a step in how a field's type was inferred, made on
+// reading the document doc: the field was first seen (from is empty), its
+// type changed from one to another, or a value of another type, seen, was
+// set aside and the type kept.
+type typeDecision struct {
+	doc  int
+	from string
+	to   string
+	seen string
+}
+
+func (d typeDecision) String() string {
+	switch {
+	case d.from == "":
+		return "first seen as " + d.to
+	case d.seen != "":
+		return fmt.Sprintf("saw %s, kept %s", d.seen, d.to)
+	case !knownType(d.from):
+		return fmt.Sprintf("promoted from %s to %s", d.from, d.to)
+	}
+	return fmt.Sprintf("changed from %s to %s", d.from, d.to)
+}
+
+// seenType returns the type of the value the decision was made on.
+func (d typeDecision) seenType() string {
+	if d.seen != "" {
+		return d.seen
+	}
+	return d.to
+}
+
+// knownType reports whether t says something about a field's values: null
+// and empty arrays do not.
+func knownType(t string) bool {
+	return t != "unknown" && t != "array<unknown>"
+}
+
+// valueType returns the type of a value as displayType names it.
+func valueType(value interface{}) string {
+	if t := getType(value); t != "array" {
+		return t
+	}
+	return "array<object>"
+}
+
+// noteType records the decision made when a value of type seen was merged
+// into f at document doc, given its type before. A type set aside is only
+// recorded the first time, so that the history stays as short as the list
+// of types the field was seen with.
+func (f *FieldInfo) noteType(doc int, before, seen string) {
+	after := displayType(f)
+	switch {
+	case after != before:
+		f.history = append(f.history, typeDecision{doc: doc, from: before, to: after})
+	case seen != after && knownType(seen):
+		if slices.ContainsFunc(f.history, func(d typeDecision) bool { return d.seen == seen }) {
+			return
+		}
+		f.history = append(f.history, typeDecision{doc: doc, from: before, to: after, seen: seen})
+	}
+}
+
+// writeExplanation prints how the type of the field at m was inferred, one
+// decision per line after a summary of the field.
+func writeExplanation(w io.Writer, m fieldMatch) error {
+	fmt.Fprintf(w, "%s: %s%s, present in %d of %d\n", m.path, displayType(m.field), fieldMarkers(m.field), m.present, m.objects)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, d := range m.field.history {
+		fmt.Fprintf(tw, "document %d\t%s\n", d.doc, d)
+	}
+	return tw.Flush()
+}
+
+// runExplain implements "json-shape explain [flags] path [input]".
+func runExplain(args []string) error {
+	fs := flag.NewFlagSet("json-shape explain", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: json-shape explain [flags] path [input]")
+		fs.PrintDefaults()
+	}
+	inputs := addInputFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return fmt.Errorf("explain takes a field path and an optional input")
+	}
+
+	if _, err := keyPath(fs.Arg(0)); err != nil {
+		return err
+	}
+	a, err := inputs.analyze(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	m, err := fieldAt(a.shape(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeExplanation(os.Stdout, m)
+}
+
+// fieldAt returns the field at path, in which "[]" may be left out.
+func fieldAt(fields map[string]*FieldInfo, path string) (fieldMatch, error) {
+	keys, err := keyPath(path)
+	if err != nil {
+		return fieldMatch{}, err
+	}
+	for _, m := range findFields(fields, func(string) bool { return true }) {
+		if k, _ := keyPath(m.path); k == keys {
+			return m, nil
+		}
+	}
+	return fieldMatch{}, fmt.Errorf("no field %s", path)
+}
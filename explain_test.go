@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestTypeHistory(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{"a": nil, "items": []interface{}{map[string]interface{}{"p": 1.0}}},
+		map[string]interface{}{"a": "x", "items": []interface{}{map[string]interface{}{"p": "s"}, map[string]interface{}{"p": 2.0}}},
+		map[string]interface{}{"a": 5.0, "items": []interface{}{}},
+		map[string]interface{}{"a": 6.0},
+		map[string]interface{}{"a": []interface{}{map[string]interface{}{"z": 1.0}}},
+	}
+	matches := findFields(analyzeJSON(data), func(string) bool { return true })
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"a", []string{
+			"1 first seen as unknown",
+			"2 promoted from unknown to string",
+			"3 saw number, kept string",
+			"5 changed from string to array<object>",
+		}},
+		{"items", []string{"1 first seen as array<object>"}},
+		{"items[].p", []string{"1 first seen as number", "2 saw string, kept number"}},
+		{"a[].z", []string{"5 first seen as number"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			i := slices.IndexFunc(matches, func(m fieldMatch) bool { return m.path == tt.path })
+			if i < 0 {
+				t.Fatalf("no field %s", tt.path)
+			}
+			var got []string
+			for _, d := range matches[i].field.history {
+				got = append(got, fmt.Sprintf("%d %s", d.doc, d))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("history = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteExplanation(t *testing.T) {
+	fields := analyzeJSON([]interface{}{
+		map[string]interface{}{"id": nil, "v": 1.0},
+		map[string]interface{}{"id": 1.0, "v": 1.0},
+		map[string]interface{}{"v": 1.0},
+	})
+	m, err := fieldAt(fields, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeExplanation(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	want := `id: number (optional, nullable), present in 2 of 3
+document 1  first seen as unknown
+document 2  promoted from unknown to number
+`
+	if buf.String() != want {
+		t.Errorf("writeExplanation() =\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestFieldAt(t *testing.T) {
+	fields := analyzeJSON(map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"id": 1.0}},
+	})
+
+	tests := []struct {
+		path    string
+		want    string // path found
+		wantErr bool
+	}{
+		{"items[].id", "items[].id", false},
+		{"items.id", "items[].id", false},
+		{`["items"][].id`, "items[].id", false},
+		{"items[0].id", "", true},
+		{"id", "", true},
+	}
+	for _, tt := range tests {
+		m, err := fieldAt(fields, tt.path)
+		if (err != nil) != tt.wantErr || m.path != tt.want {
+			t.Errorf("fieldAt(%s) = %q, %v", tt.path, m.path, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// fieldFilter selects fields by path patterns, written in the syntax of
+// field paths. Each bare key of a pattern is a glob matched against one key
+// ("*" matches any key), "**" matches any number of keys, quoted keys match
+// literally, and "[]" after a key is optional, so "items.id" and
+// "items[].id" are the same pattern.
+type fieldFilter struct {
+	include [][]pathSegment
+	exclude [][]pathSegment
+}
+
+// parseFilter parses comma-separated include and exclude pattern lists.
+func parseFilter(include, exclude string) (*fieldFilter, error) {
+	f := &fieldFilter{}
+	var err error
+	if f.include, err = parsePatterns(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = parsePatterns(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func parsePatterns(list string) ([][]pathSegment, error) {
+	var patterns [][]pathSegment
+	for _, pattern := range strings.Split(list, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		segments, err := parsePath(pattern)
+		if err != nil {
+			return nil, err
+		}
+		var keys []pathSegment
+		for _, segment := range segments {
+			if segment.element {
+				if segment.index >= 0 {
+					return nil, fmt.Errorf("invalid pattern %q: patterns match every element, write [] instead of [%d]", pattern, segment.index)
+				}
+				continue
+			}
+			if _, err := path.Match(segment.key, ""); err != nil && !segment.quoted {
+				return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+			keys = append(keys, segment)
+		}
+		patterns = append(patterns, keys)
+	}
+	return patterns, nil
+}
+
+// apply removes the fields that are excluded, or that are not included when
+// include patterns are given. Ancestors of included fields are kept so the
+// included fields stay reachable, and descendants of included fields are
+// kept too.
+func (f *fieldFilter) apply(fields map[string]*FieldInfo) {
+	f.prune(fields, nil, len(f.include) == 0)
+}
+
+func (f *fieldFilter) prune(fields map[string]*FieldInfo, parent []string, parentIncluded bool) bool {
+	kept := false
+	for key, field := range fields {
+		keys := append(parent[:len(parent):len(parent)], key)
+		if matchesAny(f.exclude, keys) {
+			delete(fields, key)
+			continue
+		}
+
+		included := parentIncluded || matchesAny(f.include, keys)
+		childKept := f.prune(field.Children, keys, included)
+		if !included && !childKept {
+			delete(fields, key)
+			continue
+		}
+		kept = true
+	}
+	return kept
+}
+
+func matchesAny(patterns [][]pathSegment, keys []string) bool {
+	for _, pattern := range patterns {
+		if matchSegments(pattern, keys) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern []pathSegment, keys []string) bool {
+	if len(pattern) == 0 {
+		return len(keys) == 0
+	}
+	if pattern[0].key == "**" && !pattern[0].quoted {
+		for i := 0; i <= len(keys); i++ {
+			if matchSegments(pattern[1:], keys[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(keys) == 0 {
+		return false
+	}
+	ok := pattern[0].key == keys[0]
+	if !pattern[0].quoted {
+		ok, _ = path.Match(pattern[0].key, keys[0])
+	}
+	return ok && matchSegments(pattern[1:], keys[1:])
+}
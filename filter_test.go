@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func filterPaths(fields map[string]*FieldInfo, parent string) []string {
+	var paths []string
+	for key, field := range fields {
+		path := fieldPath(parent, key)
+		paths = append(paths, path)
+		paths = append(paths, filterPaths(field.Children, childPath(path, field))...)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestFieldFilter(t *testing.T) {
+	data := map[string]interface{}{
+		"id": 1.0,
+		"metadata": map[string]interface{}{
+			"trace": "abc",
+			"debug": map[string]interface{}{"timing": 1.0},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"id": 1.0, "debug": true, "name": "x"},
+		},
+		"user": map[string]interface{}{
+			"id":    2.0,
+			"email": "a@example.com",
+		},
+	}
+
+	tests := []struct {
+		include, exclude string
+		expected         string
+	}{
+		{"", "", "id,items,items[].debug,items[].id,items[].name,metadata,metadata.debug,metadata.debug.timing,metadata.trace,user,user.email,user.id"},
+		{"", "metadata.*,**.debug", "id,items,items[].id,items[].name,metadata,user,user.email,user.id"},
+		{"", "metadata,user.email", "id,items,items[].debug,items[].id,items[].name,user,user.id"},
+		{"**.id", "", "id,items,items[].id,user,user.id"},
+		{"user", "", "user,user.email,user.id"},
+		{"items[].n*,metadata", "metadata.debug", "items,items[].name,metadata,metadata.trace"},
+	}
+
+	for _, tt := range tests {
+		filter, err := parseFilter(tt.include, tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := analyzeJSON(data)
+		filter.apply(fields)
+
+		if got := strings.Join(filterPaths(fields, ""), ","); got != tt.expected {
+			t.Errorf("include %q exclude %q:\n got %s\nwant %s", tt.include, tt.exclude, got, tt.expected)
+		}
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	if _, err := parseFilter("user.[", ""); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestFieldFilterQuotedKeys(t *testing.T) {
+	data := map[string]interface{}{
+		"a.b": 1.0,
+		"a":   map[string]interface{}{"b": 2.0, "*": 3.0},
+	}
+
+	tests := []struct {
+		include, exclude string
+		expected         string
+	}{
+		{"", `["a.b"]`, `a,a.b,a["*"]`},
+		{"", "a.b", `["a.b"],a,a["*"]`},
+		{"", `a["*"]`, `["a.b"],a,a.b`},
+		{"", "a.*", `["a.b"],a`},
+	}
+	for _, tt := range tests {
+		filter, err := parseFilter(tt.include, tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := analyzeJSON(data)
+		filter.apply(fields)
+
+		if got := strings.Join(filterPaths(fields, ""), ","); got != tt.expected {
+			t.Errorf("include %q exclude %q:\n got %s\nwant %s", tt.include, tt.exclude, got, tt.expected)
+		}
+	}
+
+	if _, err := parseFilter("items[0].id", ""); err == nil {
+		t.Error("expected an error for an element index in a pattern")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// fieldMatch is a field found by find, with the number of objects it is
+// present in out of those that could hold it.
+type fieldMatch struct {
+	path    string
+	field   *FieldInfo
+	present int
+	objects int
+}
+
+// findFields returns every field in the shape whose key matches, in output
+// order, depth first.
+func findFields(fields map[string]*FieldInfo, match func(key string) bool) []fieldMatch {
+	var matches []fieldMatch
+	var walk func(fields map[string]*FieldInfo, parent *FieldInfo, path string)
+	walk = func(fields map[string]*FieldInfo, parent *FieldInfo, path string) {
+		objects := parentCount(parent, fields)
+		for _, key := range sortedKeys(fields) {
+			field := fields[key]
+			p := fieldPath(path, key)
+			if match(key) {
+				matches = append(matches, fieldMatch{path: p, field: field, present: field.count, objects: objects})
+			}
+			walk(field.Children, field, childPath(p, field))
+		}
+	}
+	walk(fields, nil, "")
+	return matches
+}
+
+// keyMatcher matches keys against pattern: a regular expression when it is
+// written between slashes ("/^e-?mail$/"), otherwise a key name compared
+// without regard to case.
+func keyMatcher(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	return func(key string) bool {
+		return strings.EqualFold(key, pattern)
+	}, nil
+}
+
+// writeMatches prints one aligned line per match: path, type and presence.
+func writeMatches(w io.Writer, matches []fieldMatch) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, m := range matches {
+		presence := fmt.Sprintf("present in %d of %d", m.present, m.objects)
+		if m.objects > 0 {
+			presence += fmt.Sprintf(" (%.0f%%)", 100*float64(min(m.present, m.objects))/float64(m.objects))
+		}
+		if m.field.Nullable {
+			presence += ", sometimes null"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", m.path, displayType(m.field), presence)
+	}
+	return tw.Flush()
+}
+
+// runFind implements "json-shape find [flags] [input] pattern".
+func runFind(args []string) error {
+	fs := flag.NewFlagSet("json-shape find", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: json-shape find [flags] [input] pattern")
+		fs.PrintDefaults()
+	}
+	inputs := addInputFlags(fs)
+	fs.Parse(args)
+
+	var location, pattern string
+	switch fs.NArg() {
+	case 1:
+		pattern = fs.Arg(0)
+	case 2:
+		location, pattern = fs.Arg(0), fs.Arg(1)
+	default:
+		fs.Usage()
+		return fmt.Errorf("find takes an optional input and a pattern")
+	}
+
+	match, err := keyMatcher(pattern)
+	if err != nil {
+		return err
+	}
+	a, err := inputs.analyze(location)
+	if err != nil {
+		return err
+	}
+
+	matches := findFields(a.shape(), match)
+	if len(matches) == 0 {
+		return fmt.Errorf("no fields match %s", pattern)
+	}
+	return writeMatches(os.Stdout, matches)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestFindFields(t *testing.T) {
+	var data interface{}
+	json.Unmarshal([]byte(`[
+		{"email": "a@x", "contacts": [{"Email": "b@x", "name": "B"}, {"name": "C"}, {"name": "D"}]},
+		{"email": null, "billing": {"email_address": "c@x"}}
+	]`), &data)
+	fields := analyzeJSON(data)
+
+	match, err := keyMatcher("email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := writeMatches(&out, findFields(fields, match)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "contacts[].Email  string  present in 1 of 3 (33%)\n" +
+		"email             string  present in 2 of 2 (100%), sometimes null\n"
+	if out.String() != expected {
+		t.Errorf("writeMatches() =\n%s\nwant\n%s", out.String(), expected)
+	}
+
+	match, err = keyMatcher("/^email/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range findFields(fields, match) {
+		paths = append(paths, m.path)
+	}
+	if len(paths) != 2 || paths[0] != "billing.email_address" || paths[1] != "email" {
+		t.Errorf("regex matches = %v; want [billing.email_address email]", paths)
+	}
+
+	if _, err := keyMatcher("/(/"); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
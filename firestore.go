@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	firestoreScope       = "https://www.googleapis.com/auth/datastore"
+	defaultFirestoreSize = 100
+)
+
+// firestoreEndpoint is the Firestore REST API root; a variable so tests can
+// substitute a server. FIRESTORE_EMULATOR_HOST overrides it at run time.
+var firestoreEndpoint = "https://firestore.googleapis.com/v1"
+
+// firestoreBundleDecoder decodes a Firestore bundle: a sequence of JSON
+// elements, each preceded by its length in bytes. Every document element
+// yields its fields converted from Firestore values; metadata and query
+// elements are skipped.
+type firestoreBundleDecoder struct {
+	r *bufio.Reader
+}
+
+func newFirestoreBundleDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return &firestoreBundleDecoder{r: newBufferedReader(r)}
+}
+
+func (d *firestoreBundleDecoder) Decode() (interface{}, error) {
+	for {
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		buf, err := readBytes(d.r, n)
+		if err != nil {
+			return nil, fmt.Errorf("firestore: %w", err)
+		}
+
+		var element struct {
+			Document *struct {
+				Fields map[string]interface{} `json:"fields"`
+			} `json:"document"`
+		}
+		if err := json.Unmarshal(buf, &element); err != nil {
+			return nil, fmt.Errorf("firestore: %v", err)
+		}
+		if element.Document != nil {
+			return firestoreFields(element.Document.Fields), nil
+		}
+	}
+}
+
+// length reads the decimal length prefix of the next element, returning
+// io.EOF at a clean end of the bundle.
+func (d *firestoreBundleDecoder) length() (uint64, error) {
+	var digits []byte
+	for {
+		b, err := d.r.ReadByte()
+		if err == io.EOF && len(digits) == 0 {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fmt.Errorf("firestore: %w", unexpectedEOF(err))
+		}
+		if b >= '0' && b <= '9' {
+			digits = append(digits, b)
+			continue
+		}
+		if len(digits) == 0 && (b == ' ' || b == '\n' || b == '\r' || b == '\t') {
+			continue
+		}
+		d.r.UnreadByte()
+		n, err := strconv.ParseUint(string(digits), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("firestore: invalid element length %q", digits)
+		}
+		return n, nil
+	}
+}
+
+// firestoreFields converts a Firestore document's fields to a plain object.
+func firestoreFields(fields map[string]interface{}) map[string]interface{} {
+	obj := make(map[string]interface{}, len(fields))
+	for key, v := range fields {
+		value, _ := v.(map[string]interface{})
+		obj[key] = firestoreValue(value)
+	}
+	return obj
+}
+
+// firestoreValue converts a Firestore Value, in its REST JSON form such as
+// {"integerValue": "42"}, to the generic form analyzeJSON works on.
+// Integers become numbers; timestamps, references and base64 bytes stay
+// strings; geo points become {latitude, longitude} objects.
+func firestoreValue(v map[string]interface{}) interface{} {
+	for kind, raw := range v {
+		switch kind {
+		case "integerValue":
+			s, _ := raw.(string)
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n
+			}
+			return raw
+		case "doubleValue":
+			// NaN and the infinities are encoded as strings, and stay
+			// strings as they do in the binary encodings.
+			if s, ok := raw.(string); ok {
+				if n, err := strconv.ParseFloat(s, 64); err == nil {
+					return floatValue(n)
+				}
+			}
+			return raw
+		case "mapValue":
+			m, _ := raw.(map[string]interface{})
+			fields, _ := m["fields"].(map[string]interface{})
+			return firestoreFields(fields)
+		case "arrayValue":
+			m, _ := raw.(map[string]interface{})
+			values, _ := m["values"].([]interface{})
+			arr := make([]interface{}, 0, len(values))
+			for _, item := range values {
+				value, _ := item.(map[string]interface{})
+				arr = append(arr, firestoreValue(value))
+			}
+			return arr
+		case "nullValue":
+			return nil
+		case "stringValue", "booleanValue", "timestampValue", "referenceValue", "bytesValue", "geoPointValue":
+			return raw
+		}
+	}
+	return nil
+}
+
+// firestoreSources resolves firestore://project/collection to a sample of
+// the documents in the collection (or a subcollection path such as
+// users/alice/orders), converted to JSON. The limit query parameter sets
+// the sample size.
+func firestoreSources(input string) ([]source, error) {
+	u, err := url.Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	project, collection := u.Host, strings.Trim(u.Path, "/")
+	if project == "" || collection == "" {
+		return nil, fmt.Errorf("invalid Firestore location %q (want firestore://project/collection)", input)
+	}
+	limit := defaultFirestoreSize
+	if s := u.Query().Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid Firestore limit %q", s)
+		}
+	}
+
+	return []source{{
+		name: input,
+		open: func() (io.ReadCloser, error) {
+			docs, err := sampleFirestore(project, collection, limit)
+			if err != nil {
+				return nil, fmt.Errorf("sampling Firestore: %v", err)
+			}
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			for _, doc := range docs {
+				if err := enc.Encode(doc); err != nil {
+					return nil, fmt.Errorf("sampling Firestore: %v", err)
+				}
+			}
+			return io.NopCloser(&buf), nil
+		},
+	}}, nil
+}
+
+// sampleFirestore lists up to limit documents of collection, following page
+// tokens.
+func sampleFirestore(project, collection string, limit int) ([]map[string]interface{}, error) {
+	endpoint, token := firestoreEndpoint, ""
+	if host := os.Getenv("FIRESTORE_EMULATOR_HOST"); host != "" {
+		endpoint = "http://" + host + "/v1"
+	} else {
+		var err error
+		if token, err = googleAccessToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	var docs []map[string]interface{}
+	pageToken := ""
+	for len(docs) < limit {
+		params := url.Values{"pageSize": {strconv.Itoa(min(limit-len(docs), 300))}}
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+		listURL := fmt.Sprintf("%s/projects/%s/databases/(default)/documents/%s?%s", endpoint, project, collection, params.Encode())
+		req, err := http.NewRequest("GET", listURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Documents []struct {
+				Fields map[string]interface{} `json:"fields"`
+			} `json:"documents"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing documents: status %d", resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listing documents: %v", err)
+		}
+
+		for _, doc := range page.Documents {
+			if len(docs) == limit {
+				break
+			}
+			docs = append(docs, firestoreFields(doc.Fields))
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	return docs, nil
+}
+
+// googleAccessToken exchanges a signed JWT for an OAuth access token, using
+// the service account key file named by GOOGLE_APPLICATION_CREDENTIALS.
+func googleAccessToken() (string, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		return "", fmt.Errorf("set GOOGLE_APPLICATION_CREDENTIALS to a service account key file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var key struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("service account key: %v", err)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	assertion, err := signServiceAccountJWT(key.ClientEmail, key.PrivateKey, key.TokenURI)
+	if err != nil {
+		return "", fmt.Errorf("service account key: %v", err)
+	}
+	resp, err := http.PostForm(key.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting access token: status %d", resp.StatusCode)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	return body.AccessToken, nil
+}
+
+// signServiceAccountJWT builds the RS256-signed assertion of the OAuth 2.0
+// JWT bearer grant.
+func signServiceAccountJWT(email, privateKey, audience string) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", fmt.Errorf("no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("private key is not RSA")
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   email,
+		"scope": firestoreScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
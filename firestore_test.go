@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bundleElement frames one bundle element with its length prefix.
+func bundleElement(element string) string {
+	return fmt.Sprintf("%d%s", len(element), element)
+}
+
+const firestoreUserFields = `{
+	"name": {"stringValue": "Ada"},
+	"age": {"integerValue": "36"},
+	"score": {"doubleValue": "NaN"},
+	"active": {"booleanValue": true},
+	"deleted": {"nullValue": null},
+	"joined": {"timestampValue": "2024-01-01T00:00:00Z"},
+	"team": {"referenceValue": "projects/p/databases/(default)/documents/teams/t1"},
+	"home": {"geoPointValue": {"latitude": 51.5, "longitude": -0.1}},
+	"tags": {"arrayValue": {"values": [{"stringValue": "admin"}]}},
+	"prefs": {"mapValue": {"fields": {"theme": {"stringValue": "dark"}}}}
+}`
+
+func TestFirestoreBundleDecoder(t *testing.T) {
+	input := bundleElement(`{"metadata":{"id":"users","totalDocuments":2}}`) +
+		bundleElement(`{"documentMetadata":{"name":"projects/p/databases/(default)/documents/users/ada","exists":true}}`) +
+		bundleElement(`{"document":{"name":"projects/p/databases/(default)/documents/users/ada","fields":`+firestoreUserFields+`}}`) +
+		"\n" + bundleElement(`{"document":{"name":"projects/p/databases/(default)/documents/users/bob","fields":{"name":{"stringValue":"Bob"}}}}`)
+
+	docs, err := decodeAll(newFirestoreBundleDecoder(strings.NewReader(input), nil))
+	if err != nil {
+		t.Fatalf("decodeAll() error: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+
+	fields := analyzeJSON(docs)
+	expected := map[string]string{
+		"name":    "string",
+		"age":     "number",
+		"score":   "string",
+		"active":  "boolean",
+		"joined":  "string",
+		"team":    "string",
+		"tags":    "array<string>",
+		"deleted": "unknown",
+	}
+	for name, typ := range expected {
+		if fields[name] == nil || fields[name].Type != typ {
+			t.Errorf("field %s: got %+v; want type %s", name, fields[name], typ)
+		}
+	}
+	if fields["home"] == nil || fields["home"].Children["latitude"] == nil {
+		t.Errorf("expected geo point to become an object, got %+v", fields["home"])
+	}
+	if fields["prefs"] == nil || fields["prefs"].Children["theme"] == nil {
+		t.Errorf("expected map value to become an object, got %+v", fields["prefs"])
+	}
+	if !fields["age"].Optional || fields["name"].Optional {
+		t.Error("expected fields missing from the second document to be optional")
+	}
+}
+
+func TestFirestoreBundleDecoderTruncated(t *testing.T) {
+	input := bundleElement(`{"document":{"fields":{}}}`)
+	if _, err := decodeAll(newFirestoreBundleDecoder(strings.NewReader(input[:len(input)-3]), nil)); err == nil {
+		t.Error("expected an error for a truncated element")
+	}
+}
+
+func TestFirestoreSources(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.FormValue("assertion"), ".") != 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"tok"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" || r.URL.Path != "/v1/projects/p/databases/(default)/documents/users" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"documents":[{"fields":{"name":{"stringValue":"Ada"}}}],"nextPageToken":"next"}`)
+			return
+		}
+		fmt.Fprint(w, `{"documents":[{"fields":{"name":{"stringValue":"Bob"},"age":{"integerValue":"7"},"score":{"doubleValue":"Infinity"}}}, {"fields":{}}]}`)
+	}))
+	defer server.Close()
+
+	oldEndpoint := firestoreEndpoint
+	firestoreEndpoint = server.URL + "/v1"
+	defer func() { firestoreEndpoint = oldEndpoint }()
+
+	credentials, _ := json.Marshal(map[string]string{
+		"client_email": "sampler@p.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(keyFile, credentials, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", keyFile)
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "")
+
+	sources, err := resolveSources("firestore://p/users?limit=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := sources[0].open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	docs, err := decodeAll(newJSONDecoder(r, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"name": "Ada"},
+		map[string]interface{}{"name": "Bob", "age": 7.0, "score": "Infinity"},
+	}
+	if !reflect.DeepEqual(docs, expected) {
+		t.Errorf("sampled %#v; want %#v (limited to 2)", docs, expected)
+	}
+}
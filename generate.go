@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// minReplicaValueCount is how often each value of a string field must have
+// been seen for replicas to reuse the values with --keep-categories.
+const minReplicaValueCount = 5
+
+// maxReplicaCategoryLength is the length of the longest string replicas
+// reuse: categories are short codes such as statuses, where longer values
+// are more likely to be names or free text.
+const maxReplicaCategoryLength = 16
+
+// identifyingFormats are the stringFormats whose values may identify
+// someone however often they recur, and which replicas never reuse.
+var identifyingFormats = []string{"email", "uuid", "url", "ipv4"}
+
+// replicator generates documents with the shape and value statistics of
+// analyzed ones, but none of their values: fields are present, null and true
+// as often, numbers fall in the same range around the same mean, and strings
+// have the same lengths or formats. With keepCategories, the values of
+// string fields with a few recurring short values, such as statuses, are
+// reused.
+type replicator struct {
+	rng            *rand.Rand
+	keepCategories bool
+}
+
+func newReplicator(seed uint64, keepCategories bool) *replicator {
+	return &replicator{rng: rand.New(rand.NewPCG(seed, seed)), keepCategories: keepCategories}
+}
+
+// object generates an object holding fields, each present as often as it
+// was among the given number of objects.
+func (g *replicator) object(fields map[string]*FieldInfo, objects int) map[string]interface{} {
+	doc := make(map[string]interface{}, len(fields))
+	for _, key := range sortedKeys(fields) {
+		field := fields[key]
+		if objects > 0 && g.rng.Float64()*float64(objects) >= float64(field.count) {
+			continue
+		}
+		doc[key] = g.value(field)
+	}
+	return doc
+}
+
+func (g *replicator) value(field *FieldInfo) interface{} {
+	s := &field.stats
+	typed := s.numbers + s.strings + s.booleans + s.arrays
+	if field.Type == "" && !field.isArray {
+		typed += field.objects
+	}
+	if nulls := field.count - typed; field.hasNull && g.rng.Float64()*float64(field.count) < float64(nulls) {
+		return nil
+	}
+
+	switch {
+	case field.Type == "" && field.isArray:
+		items := make([]interface{}, g.items(s))
+		for i := range items {
+			items[i] = g.object(field.Children, field.objects)
+		}
+		return items
+	case field.Type == "":
+		return g.object(field.Children, field.objects)
+	case field.Type == "number":
+		return g.number(s)
+	case field.Type == "string":
+		return g.string(s)
+	case field.Type == "boolean":
+		return s.booleans > 0 && g.rng.Float64()*float64(s.booleans) < float64(s.trues)
+	case strings.HasPrefix(field.Type, "array"):
+		return g.array(field.Type, g.items(s))
+	}
+	// Fields only ever seen null.
+	return nil
+}
+
+// array makes up n elements of an array of type typ, as getType names
+// array types: "array<number>", "array<array<string>>", or "array" for an
+// array of objects nested in another array. Elements have no statistics of
+// their own, only a type.
+func (g *replicator) array(typ string, n int) []interface{} {
+	elem := "object"
+	if typ != "array" {
+		elem = strings.TrimSuffix(strings.TrimPrefix(typ, "array<"), ">")
+	}
+	items := make([]interface{}, 0, n)
+	for range n {
+		items = append(items, g.element(elem))
+	}
+	return items
+}
+
+// element makes up an array element of type typ. Nested arrays get at least
+// one element, the type of the first element naming their type; objects in
+// nested arrays have no fields, as the shape records none.
+func (g *replicator) element(typ string) interface{} {
+	switch {
+	case typ == "number":
+		return float64(g.rng.IntN(100))
+	case typ == "string":
+		return g.letters(3 + g.rng.IntN(8))
+	case typ == "boolean":
+		return g.rng.IntN(2) == 0
+	case typ == "object":
+		return map[string]interface{}{}
+	case typ == "array<unknown>":
+		return []interface{}{}
+	case strings.HasPrefix(typ, "array"):
+		return g.array(typ, 1+g.rng.IntN(3))
+	}
+	return nil
+}
+
+// items returns an array length in the range seen.
+func (g *replicator) items(s *fieldStats) int {
+	if s.arrays == 0 {
+		return 0
+	}
+	return s.minItems + g.rng.IntN(s.maxItems-s.minItems+1)
+}
+
+// number draws from the triangular distribution over the range seen whose
+// mean is closest to the mean seen, rounding when every number seen was an
+// integer.
+func (g *replicator) number(s *fieldStats) float64 {
+	if s.numbers == 0 {
+		return 0
+	}
+	lo, hi := s.min, s.max
+	if lo == hi {
+		return g.jitter(lo, !s.fractions)
+	}
+	mode := min(max(3*s.mean()-lo-hi, lo), hi)
+	var v float64
+	if u := g.rng.Float64(); u < (mode-lo)/(hi-lo) {
+		v = lo + math.Sqrt(u*(hi-lo)*(mode-lo))
+	} else {
+		v = hi - math.Sqrt((1-u)*(hi-lo)*(hi-mode))
+	}
+	if !s.fractions {
+		v = math.Round(v)
+	}
+	return v
+}
+
+// jitter returns a number near v, within a tenth of it or 1, but never v
+// itself: a field that always held the same number could give it away.
+func (g *replicator) jitter(v float64, integer bool) float64 {
+	offset := max(math.Abs(v)/10, 1) * (0.1 + 0.9*g.rng.Float64())
+	if integer {
+		offset = max(math.Round(offset), 1)
+	}
+	if g.rng.IntN(2) == 0 {
+		offset = -offset
+	}
+	return v + offset
+}
+
+// string reuses the values of a categorical field in proportion, fakes a
+// value of the format every string had, or else makes up letters of a
+// length in the range seen.
+func (g *replicator) string(s *fieldStats) string {
+	if counts := s.distinct.counts(); g.categorical(s, counts) {
+		values := make([]string, 0, len(counts))
+		for v := range counts {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		pick := g.rng.IntN(s.strings)
+		for _, v := range values {
+			if pick -= counts[v]; pick < 0 {
+				return v
+			}
+		}
+	}
+
+	length := s.minLen + g.rng.IntN(s.maxLen-s.minLen+1)
+	for i, f := range stringFormats {
+		if s.misfits&(1<<i) == 0 {
+			return g.fake(f.id, length)
+		}
+	}
+	return g.letters(length)
+}
+
+// categorical reports whether the string values of a field, with counts,
+// may be reused: with --keep-categories, when there are at most
+// maxEnumValues short values, each seen minReplicaValueCount times, none
+// of an identifying format.
+func (g *replicator) categorical(s *fieldStats, counts map[string]int) bool {
+	if !g.keepCategories || len(counts) == 0 || len(counts) > maxEnumValues || s.maxLen > maxReplicaCategoryLength {
+		return false
+	}
+	for _, n := range counts {
+		if n < minReplicaValueCount {
+			return false
+		}
+	}
+	for i, f := range stringFormats {
+		if s.misfits&(1<<i) == 0 && slices.Contains(identifyingFormats, f.id) {
+			return false
+		}
+	}
+	return true
+}
+
+// Codes fake country and currency values are drawn from.
+var (
+	fakeCountries  = []string{"DE", "FR", "GB", "JP", "NL", "US"}
+	fakeCurrencies = []string{"EUR", "GBP", "JPY", "USD"}
+)
+
+// fake makes up a string of a stringFormat, of about length characters
+// where the format allows.
+func (g *replicator) fake(format string, length int) string {
+	switch format {
+	case "timestamp", "date":
+		// A time in 2020-2024.
+		t := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(g.rng.Int64N(int64(5 * 365 * 24 * time.Hour))))
+		if format == "date" {
+			return t.Format(time.DateOnly)
+		}
+		return t.Format(time.RFC3339)
+	case "uuid":
+		b := make([]byte, 16)
+		for i := range b {
+			b[i] = byte(g.rng.UintN(256))
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case "email":
+		return g.letters(max(length-len("@example.com"), 1)) + "@example.com"
+	case "url":
+		return "https://example.com/" + g.letters(max(length-len("https://example.com/"), 1))
+	case "ipv4":
+		// TEST-NET-1, reserved for documentation.
+		return fmt.Sprintf("192.0.2.%d", g.rng.IntN(256))
+	case "color":
+		return fmt.Sprintf("#%06x", g.rng.UintN(1<<24))
+	case "country":
+		return fakeCountries[g.rng.IntN(len(fakeCountries))]
+	case "currency":
+		return fakeCurrencies[g.rng.IntN(len(fakeCurrencies))]
+	case "numeric":
+		digits := make([]byte, max(length, 1))
+		for i := range digits {
+			digits[i] = byte('0' + g.rng.IntN(10))
+		}
+		return string(digits)
+	}
+	return g.letters(length)
+}
+
+func (g *replicator) letters(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('a' + g.rng.IntN(26))
+	}
+	return string(b)
+}
+
+// runGenerate implements "json-shape generate [flags] [input]". It writes
+// newline-delimited JSON documents shaped and distributed like the input,
+// with fake values.
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("json-shape generate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: json-shape generate [flags] [input]")
+		fs.PrintDefaults()
+	}
+	count := fs.Int("count", 0, "number of documents to generate (default: as many as the input holds)")
+	seed := fs.Uint64("seed", 1, "seed of the random values, so that the same input and seed give the same documents")
+	keepCategories := fs.Bool("keep-categories", false, "reuse the values of string fields with a few recurring short values, such as statuses")
+	inputs := addInputFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("generate takes an optional input")
+	}
+
+	a, err := inputs.analyze(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	objects := a.objects
+	if objects == 0 {
+		return fmt.Errorf("no objects found in input")
+	}
+	if *count <= 0 {
+		*count = objects
+	}
+
+	fields := a.shape()
+	g := newReplicator(*seed, *keepCategories)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	for range *count {
+		if err := enc.Encode(g.object(fields, objects)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
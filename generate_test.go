@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func replicaTestData() []interface{} {
+	var docs []interface{}
+	for i := range 60 {
+		doc := map[string]interface{}{
+			"id":      float64(i + 1),
+			"email":   fmt.Sprintf("person%d@corp.test", i),
+			"status":  []string{"open", "closed", "pending"}[i%3],
+			"paid":    i%4 == 0,
+			"created": fmt.Sprintf("2023-05-%02dT10:00:00Z", i%28+1),
+			"tags":    []interface{}{"a", "b"}[:i%3],
+			"items":   []interface{}{map[string]interface{}{"qty": float64(i%5 + 1)}},
+		}
+		if i%2 == 0 {
+			doc["note"] = nil
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestReplicatorShape(t *testing.T) {
+	data := replicaTestData()
+	fields := analyzeJSON(data)
+	g := newReplicator(7, true)
+	var replicas []interface{}
+	for range len(data) {
+		replicas = append(replicas, g.object(fields, len(data)))
+	}
+
+	var want, got strings.Builder
+	if err := formats["tree"](&want, fields, &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	replicaFields := analyzeJSON(replicas)
+	if err := formats["tree"](&got, replicaFields, &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("replica shape:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+
+	for _, doc := range replicas {
+		doc := doc.(map[string]interface{})
+		if email := doc["email"].(string); !strings.HasSuffix(email, "@example.com") {
+			t.Errorf("email %q is not fake", email)
+		}
+		if status := doc["status"].(string); status != "open" && status != "closed" && status != "pending" {
+			t.Errorf("status %q is not one of the values seen", status)
+		}
+		if id := doc["id"].(float64); id < 1 || id > 60 || id != float64(int(id)) {
+			t.Errorf("id %v is not an integer in the range seen", id)
+		}
+		if created := doc["created"].(string); !stringFormats[0].pattern.MatchString(created) {
+			t.Errorf("created %q is not a timestamp", created)
+		}
+	}
+	if s := replicaFields["id"].stats; s.mean() < 20 || s.mean() > 40 {
+		t.Errorf("mean id %v, want about 30.5", s.mean())
+	}
+}
+
+func TestReplicatorNestedArrays(t *testing.T) {
+	var data []interface{}
+	for i := range 10 {
+		data = append(data, map[string]interface{}{
+			"matrix": []interface{}{[]interface{}{float64(i), 2.0}, []interface{}{3.0}},
+			"groups": []interface{}{[]interface{}{map[string]interface{}{"id": float64(i)}}},
+			"words":  []interface{}{[]interface{}{[]interface{}{"a"}}},
+			"empty":  []interface{}{[]interface{}{}},
+			"none":   []interface{}{nil},
+		})
+	}
+	fields := analyzeJSON(data)
+	g := newReplicator(3, false)
+	var replicas []interface{}
+	for range len(data) {
+		replicas = append(replicas, g.object(fields, len(data)))
+	}
+
+	var want, got strings.Builder
+	if err := formats["compact"](&want, fields, &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := formats["compact"](&got, analyzeJSON(replicas), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("replica shape:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+	for _, doc := range replicas {
+		if groups := doc.(map[string]interface{})["groups"].([]interface{}); len(groups) == 0 || len(groups[0].([]interface{})) == 0 {
+			t.Errorf("groups = %v, want arrays of objects", groups)
+		}
+	}
+}
+
+func TestReplicatorSeed(t *testing.T) {
+	fields := analyzeJSON(replicaTestData())
+	a := newReplicator(1, false).object(fields, 60)
+	b := newReplicator(1, false).object(fields, 60)
+	c := newReplicator(2, false).object(fields, 60)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("the same seed gave %v and %v", a, b)
+	}
+	if reflect.DeepEqual(a, c) {
+		t.Errorf("different seeds gave the same document %v", a)
+	}
+}
+
+func TestReplicatorCopiesNoValues(t *testing.T) {
+	var data []interface{}
+	for i := range 60 {
+		data = append(data, map[string]interface{}{
+			"version": 3.0,
+			"amount":  19.99 + float64(i)*1.37,
+			"status":  []string{"open", "closed"}[i%2],
+			"region":  []string{"north-east-coastal-zone", "south-west-inland-zone"}[i%2],
+			"email":   fmt.Sprintf("person%d@corp.test", i%6),
+			"owner":   fmt.Sprintf("Person Number %d", i),
+			"account": map[string]interface{}{"balance": 1000.5 - float64(i)*3.25},
+		})
+	}
+	fields := analyzeJSON(data)
+
+	tests := []struct {
+		keepCategories bool
+		allowed        []interface{}
+	}{
+		{false, nil},
+		// Only the short status codes are reused: region values are too
+		// long and email addresses identify someone.
+		{true, []interface{}{"open", "closed"}},
+	}
+
+	for _, tt := range tests {
+		input := make(map[interface{}]bool)
+		for _, doc := range data {
+			collectScalars(doc, input)
+		}
+		for _, v := range tt.allowed {
+			delete(input, v)
+		}
+
+		g := newReplicator(1, tt.keepCategories)
+		for range 200 {
+			output := make(map[interface{}]bool)
+			collectScalars(g.object(fields, len(data)), output)
+			for v := range output {
+				if input[v] {
+					t.Errorf("keepCategories %v: replica copies input value %v", tt.keepCategories, v)
+				}
+			}
+		}
+	}
+}
+
+// collectScalars adds the strings and numbers in value to set.
+func collectScalars(value interface{}, set map[interface{}]bool) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, v := range value {
+			collectScalars(v, set)
+		}
+	case []interface{}:
+		for _, v := range value {
+			collectScalars(v, set)
+		}
+	case string, float64:
+		set[value] = true
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+const htmlHeader = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>json-shape</title>
+<style>
+body { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; margin: 2em; }
+ul { list-style: none; padding-left: 1.5em; margin: 0; }
+li { margin: 0.2em 0; }
+summary { cursor: pointer; }
+.type { color: #0550ae; }
+.optional { color: #6e7781; font-style: italic; }
+.example { color: #116329; }
+</style>
+</head>
+<body>
+<details open>
+<summary>root</summary>
+`
+
+const htmlFooter = `</details>
+</body>
+</html>
+`
+
+func writeHTML(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	fmt.Fprint(w, htmlHeader)
+	writeHTMLList(w, fields)
+	_, err := fmt.Fprint(w, htmlFooter)
+	return err
+}
+
+// writeHTMLList writes fields as a nested list where objects and arrays of
+// objects are collapsible <details> elements.
+func writeHTMLList(w io.Writer, fields map[string]*FieldInfo) {
+	fmt.Fprintln(w, "<ul>")
+	for _, key := range sortedKeys(fields) {
+		field := fields[key]
+
+		label := fmt.Sprintf(`%s: <span class="type">%s</span>`, html.EscapeString(key), html.EscapeString(displayType(field)))
+		switch {
+		case field.Assumed && field.Optional:
+			label += ` <span class="optional">(presence unknown)</span>`
+		case field.Assumed:
+			label += ` <span class="optional">(assumed required)</span>`
+		case field.Optional:
+			label += ` <span class="optional">(optional)</span>`
+		}
+		if field.Nullable {
+			label += ` <span class="optional">(nullable)</span>`
+		}
+
+		if len(field.Children) > 0 {
+			fmt.Fprintf(w, "<li><details open>\n<summary>%s</summary>\n", label)
+			writeHTMLList(w, field.Children)
+			fmt.Fprintln(w, "</details></li>")
+			continue
+		}
+		if field.example != nil {
+			label += fmt.Sprintf(` <span class="example">e.g. %s</span>`, html.EscapeString(formatExample(field.example)))
+		}
+		fmt.Fprintf(w, "<li>%s</li>\n", label)
+	}
+	fmt.Fprintln(w, "</ul>")
+}
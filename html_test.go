@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTML(t *testing.T) {
+	data := map[string]interface{}{
+		"<b>": "x",
+		"user": map[string]interface{}{
+			"avatar": nil,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeHTML(&buf, analyzeJSON(data), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := buf.String()
+	expected := []string{
+		"<!DOCTYPE html>",
+		`<li>&lt;b&gt;: <span class="type">string</span> <span class="example">e.g. &#34;x&#34;</span></li>`,
+		`<summary>user: <span class="type">object</span></summary>`,
+		`<li>avatar: <span class="type">unknown</span> <span class="optional">(nullable)</span></li>`,
+		"</html>",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "<details") != strings.Count(output, "</details>") {
+		t.Error("unbalanced <details> elements")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// source is a named input stream, opened on demand so that inputs spanning
+// many streams do not hold them all open at once.
+type source struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// resolveSources returns the streams named by input: stdin when input is
+// empty, otherwise an Azure Blob Storage location, a Firestore collection,
+// a URL or a local file.
+func resolveSources(input string) ([]source, error) {
+	switch {
+	case input == "":
+		return []source{{name: "stdin", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(os.Stdin), nil
+		}}}, nil
+	case strings.HasPrefix(input, "az://"), isAzureBlobURL(input):
+		return azureSources(input)
+	case strings.HasPrefix(input, "firestore://"):
+		return firestoreSources(input)
+	case strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://"):
+		return []source{{name: input, open: func() (io.ReadCloser, error) {
+			return fetchURL(input)
+		}}}, nil
+	default:
+		return []source{{name: input, open: func() (io.ReadCloser, error) {
+			file, err := os.Open(input)
+			if err != nil {
+				return nil, fmt.Errorf("opening file: %v", err)
+			}
+			return file, nil
+		}}}, nil
+	}
+}
+
+func fetchURL(url string) (io.ReadCloser, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching URL: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching URL: status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// inputFlags holds the command-line flags that select and configure the
+// input encoding, shared by every command that reads documents.
+type inputFlags struct {
+	input           *string
+	sheet           *string
+	protoDescriptor *string
+	protoMessage    *string
+	protoSingle     *bool
+	skipInvalid     *bool
+	maxInputBytes   *int64
+	maxDocs         *int
+	transform       *string
+
+	// keyOrder, when set, records the key order of the documents read, for
+	// --sort=original.
+	keyOrder *keyOrder
+}
+
+func addInputFlags(fs *flag.FlagSet) *inputFlags {
+	return &inputFlags{
+		input:           fs.String("input", "", "input encoding: "+inputNames()+" (default: by file extension, else json)"),
+		sheet:           fs.String("sheet", "", "spreadsheet sheet to read, by name or 1-based index (default: first sheet)"),
+		protoDescriptor: fs.String("proto-descriptor", "", "protobuf descriptor set (protoc --descriptor_set_out) describing --input=protobuf records"),
+		protoMessage:    fs.String("proto-message", "", "fully qualified protobuf message type of each record, e.g. 'acme.v1.Event'"),
+		protoSingle:     fs.Bool("proto-single", false, "read the protobuf input as one message instead of length-delimited records"),
+		skipInvalid:     fs.Bool("skip-invalid", false, "skip invalid newline-delimited JSON records, and inputs that fail to decode when there are several, reporting each on stderr"),
+		maxInputBytes:   fs.Int64("max-input-bytes", 0, "fail with status 4 when the inputs hold more bytes than this (0: no limit)"),
+		maxDocs:         fs.Int("max-docs", 0, "fail with status 4 when the inputs hold more documents than this, counting the elements of top-level arrays (0: no limit)"),
+		transform:       fs.String("transform", "", "steps run on each document before analysis, separated by ';': unwrap PATH, rename PATH KEY, drop PATH, where PATH ==|!= VALUE, where PATH exists|missing"),
+	}
+}
+
+// read decodes every document at location (stdin when empty) and merges
+// them into the value to analyze.
+func (f *inputFlags) read(location string) (interface{}, error) {
+	input, opts, err := f.options()
+	if err != nil {
+		return nil, err
+	}
+	sources, err := resolveSources(location)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := readSources(sources, input, opts, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no documents found in input")
+	}
+	return mergeDocuments(docs), nil
+}
+
+// analyze decodes every document at location (stdin when empty) and infers
+// their shape as they are read, so that memory grows with the shape rather
+// than with the input.
+func (f *inputFlags) analyze(location string) (*analyzer, error) {
+	input, opts, err := f.options()
+	if err != nil {
+		return nil, err
+	}
+	sources, err := resolveSources(location)
+	if err != nil {
+		return nil, err
+	}
+	a := newAnalyzer()
+	if err := eachSourceDocument(sources, input, opts, os.Stderr, a.add); err != nil {
+		return nil, err
+	}
+	if a.docs == 0 {
+		return nil, fmt.Errorf("no documents found in input")
+	}
+	return a, nil
+}
+
+// options returns the input encoding the flags select, or "" to detect it
+// from each input's name, and the options of its decoder.
+func (f *inputFlags) options() (string, *decodeOptions, error) {
+	input := *f.input
+	if _, ok := decoders[input]; !ok && input != "" {
+		return "", nil, fmt.Errorf("unknown input %q (want one of: %s)", input, inputNames())
+	}
+	if input == "" && *f.protoDescriptor != "" {
+		input = "protobuf"
+	}
+	opts := &decodeOptions{
+		sheet:           *f.sheet,
+		protoDescriptor: *f.protoDescriptor,
+		protoMessage:    *f.protoMessage,
+		protoSingle:     *f.protoSingle,
+		skipInvalid:     *f.skipInvalid,
+		keyOrder:        f.keyOrder,
+		maxInputBytes:   *f.maxInputBytes,
+		maxDocs:         *f.maxDocs,
+	}
+	var err error
+	if opts.transform, err = parseTransform(*f.transform); err != nil {
+		return "", nil, err
+	}
+	return input, opts, nil
+}
+
+// readSources decodes the documents of every source, detecting the input
+// encoding from each name when input is empty. With opts.skipInvalid, invalid
+// records and, when there are several sources, sources that fail to decode
+// are reported on log and skipped, followed by a count of what was skipped.
+// The documents a skipped source held before it failed are kept.
+//
+// The opts.maxInputBytes and opts.maxDocs limits apply to all sources
+// together. Going over one is an error that --skip-invalid does not skip.
+func readSources(sources []source, input string, opts *decodeOptions, log io.Writer) ([]interface{}, error) {
+	var docs []interface{}
+	err := eachSourceDocument(sources, input, opts, log, func(doc interface{}) {
+		docs = append(docs, doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// eachSourceDocument is readSources, except that each document is passed
+// to each as it is decoded and transformed, so that the documents need not
+// be held in memory together.
+func eachSourceDocument(sources []source, input string, opts *decodeOptions, log io.Writer, each func(doc interface{})) error {
+	var skippedRecords, skippedSources int
+	bytesLeft := &inputBudget{limit: opts.maxInputBytes}
+	docsLeft := &docBudget{limit: opts.maxDocs}
+	for _, src := range sources {
+		rc, err := src.open()
+		if err != nil {
+			return err
+		}
+		var reader io.Reader = rc
+		if bytesLeft.limit > 0 {
+			reader = &budgetReader{r: rc, b: bytesLeft}
+		}
+		encoding := input
+		if encoding == "" {
+			encoding = inputForName(src.name)
+		}
+
+		dec := decoders[encoding](reader, opts)
+		if docsLeft.limit > 0 {
+			dec = &budgetDecoder{dec: dec, b: docsLeft}
+		}
+		var onInvalid func(error)
+		if opts.skipInvalid {
+			onInvalid = func(err error) {
+				skippedRecords++
+				fmt.Fprintf(log, "warning: %s: skipping invalid record: %v\n", src.name, err)
+			}
+		}
+		// A transform that fails is no decoding error, which --skip-invalid
+		// could skip.
+		var transformErr error
+		err = decodeEach(dec, onInvalid, func(doc interface{}) error {
+			docs, err := opts.transform.apply([]interface{}{doc})
+			if err != nil {
+				transformErr = err
+				return err
+			}
+			for _, doc := range docs {
+				each(doc)
+			}
+			return nil
+		})
+		rc.Close()
+		var lerr *limitError
+		switch {
+		case bytesLeft.exceeded:
+			return fmt.Errorf("%s: %w", src.name, bytesLeft.err())
+		case docsLeft.exceeded, errors.As(err, &lerr):
+			return fmt.Errorf("%s: %w", src.name, err)
+		case transformErr != nil:
+			return fmt.Errorf("%s: %v", src.name, transformErr)
+		}
+		if err != nil && opts.skipInvalid && len(sources) > 1 {
+			skippedSources++
+			fmt.Fprintf(log, "warning: %s: skipping input: %v\n", src.name, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("parsing %s: %v", src.name, err)
+		}
+	}
+
+	if skippedRecords > 0 || skippedSources > 0 {
+		fmt.Fprintf(log, "skipped %d invalid records and %d inputs\n", skippedRecords, skippedSources)
+	}
+	// Key order is recorded as decoded, before the transform moves keys.
+	opts.transform.reorder(opts.keyOrder)
+	return nil
+}
+
+// mergeDocuments combines the documents read from all inputs into the value
+// to analyze. A single document is analyzed as is; several are merged as the
+// objects of one array, flattening documents that are arrays themselves.
+func mergeDocuments(docs []interface{}) interface{} {
+	if len(docs) == 1 {
+		return docs[0]
+	}
+	merged := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		if items, ok := doc.([]interface{}); ok {
+			merged = append(merged, items...)
+		} else {
+			merged = append(merged, doc)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMergeDocuments(t *testing.T) {
+	single := map[string]interface{}{"a": 1.0}
+	if got := mergeDocuments([]interface{}{single}); got == nil {
+		t.Error("single document should be returned as is")
+	}
+
+	merged := mergeDocuments([]interface{}{
+		single,
+		[]interface{}{map[string]interface{}{"a": 2.0}, map[string]interface{}{"a": 3.0}},
+	})
+	if items, ok := merged.([]interface{}); !ok || len(items) != 3 {
+		t.Errorf("expected 3 merged objects, got %#v", merged)
+	}
+}
+
+func TestReadSourcesSkipInvalid(t *testing.T) {
+	sources := []source{
+		{name: "good.ndjson", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("{\"a\": 1}\n{oops}\n{\"a\": 2}\n")), nil
+		}},
+		{name: "bad.bson", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("\x01\x00")), nil
+		}},
+	}
+
+	var log strings.Builder
+	docs, err := readSources(sources, "", &decodeOptions{skipInvalid: true}, &log)
+	if err != nil {
+		t.Fatalf("readSources() error: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Errorf("expected 2 documents, got %#v", docs)
+	}
+	for _, want := range []string{
+		"warning: good.ndjson: skipping invalid record: line 2, column 2",
+		"warning: bad.bson: skipping input:",
+		"skipped 1 invalid records and 1 inputs",
+	} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("log missing %q:\n%s", want, log.String())
+		}
+	}
+
+	if _, err := readSources(sources, "", &decodeOptions{}, &log); err == nil || !strings.Contains(err.Error(), "parsing good.ndjson") {
+		t.Errorf("expected the first invalid record to fail without --skip-invalid, got %v", err)
+	}
+}
+
+func TestReadSourcesTransformError(t *testing.T) {
+	// A failing transform is not a decoding error, which --skip-invalid
+	// would skip.
+	sources := []source{
+		{name: "a.ndjson", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("{\"a\": 1}\n{\"a\": 1, \"b\": 2}\n")), nil
+		}},
+		{name: "b.ndjson", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("{\"a\": 3}\n")), nil
+		}},
+	}
+	transform, err := parseTransform("rename a b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readSources(sources, "", &decodeOptions{skipInvalid: true, transform: transform}, io.Discard)
+	if err == nil || !strings.HasPrefix(err.Error(), "a.ndjson: --transform: rename a b") {
+		t.Errorf("readSources() error = %v; want the transform error", err)
+	}
+}
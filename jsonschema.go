@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is the subset of JSON Schema the shape maps onto. Fields are
+// declared in the order they are conventionally written.
+type jsonSchema struct {
+	Schema      string           `json:"$schema,omitempty"`
+	Ref         string           `json:"$ref,omitempty"`
+	Type        interface{}      `json:"type,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Enum        []interface{}    `json:"enum,omitempty"`
+	Properties  schemaProperties `json:"properties,omitzero"`
+	Required    []string         `json:"required,omitempty"`
+	Items       *jsonSchema      `json:"items,omitempty"`
+	Minimum     *float64         `json:"minimum,omitempty"`
+	Maximum     *float64         `json:"maximum,omitempty"`
+	MinLength   *int             `json:"minLength,omitempty"`
+	MaxLength   *int             `json:"maxLength,omitempty"`
+	MinItems    *int             `json:"minItems,omitempty"`
+	MaxItems    *int             `json:"maxItems,omitempty"`
+}
+
+// schemaProperties keeps properties in output order, which a Go map would
+// lose when marshaled.
+type schemaProperties struct {
+	keys    []string
+	schemas map[string]*jsonSchema
+}
+
+func (p schemaProperties) IsZero() bool {
+	return len(p.keys) == 0
+}
+
+func (p schemaProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range p.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(p.schemas[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (p *schemaProperties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("properties: want an object")
+	}
+	p.keys, p.schemas = nil, make(map[string]*jsonSchema)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		var schema jsonSchema
+		if err := dec.Decode(&schema); err != nil {
+			return fmt.Errorf("properties: %s: %v", key, err)
+		}
+		if _, dup := p.schemas[key]; !dup {
+			p.keys = append(p.keys, key)
+		}
+		p.schemas[key] = &schema
+	}
+	return nil
+}
+
+// writeJSONSchema writes a schema describing each object of the input. With
+// stats enabled, observed value ranges become minimum, maximum, minLength,
+// maxLength, minItems and maxItems constraints, and strings with few
+// distinct values an enum.
+func writeJSONSchema(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	schema := objectSchema(fields, opts)
+	schema.Schema = jsonSchemaDialect
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func objectSchema(fields map[string]*FieldInfo, opts *renderOptions) *jsonSchema {
+	schema := &jsonSchema{
+		Type:       "object",
+		Properties: schemaProperties{schemas: make(map[string]*jsonSchema)},
+	}
+	for _, key := range sortedKeys(fields) {
+		field := fields[key]
+		schema.Properties.keys = append(schema.Properties.keys, key)
+		schema.Properties.schemas[key] = fieldSchema(field, opts)
+		if !field.Optional {
+			schema.Required = append(schema.Required, key)
+		}
+	}
+	return schema
+}
+
+func fieldSchema(field *FieldInfo, opts *renderOptions) *jsonSchema {
+	var schema *jsonSchema
+	switch {
+	case field.Type == "" && field.isArray:
+		schema = &jsonSchema{Type: "array", Items: objectSchema(field.Children, opts)}
+	case field.Type == "":
+		schema = objectSchema(field.Children, opts)
+	default:
+		schema = typeSchema(field.Type)
+	}
+
+	if field.hasNull && schema.Type != "null" {
+		schema.Type = []interface{}{schema.Type, "null"}
+	}
+	if opts.stats {
+		addStatsConstraints(schema, &field.stats)
+		addEnum(schema, field)
+	}
+	if opts.describe {
+		schema.Description = describeField(field, opts)
+	}
+	return schema
+}
+
+// typeSchema returns the schema of a leaf type such as "string" or
+// "array<number>".
+func typeSchema(typ string) *jsonSchema {
+	if strings.HasPrefix(typ, "array<") && strings.HasSuffix(typ, ">") {
+		schema := &jsonSchema{Type: "array"}
+		if elem := typ[len("array<") : len(typ)-1]; elem != "unknown" {
+			schema.Items = typeSchema(elem)
+		}
+		return schema
+	}
+	switch typ {
+	case "string", "number", "boolean":
+		return &jsonSchema{Type: typ}
+	case "unknown":
+		return &jsonSchema{Type: "null"}
+	}
+	return &jsonSchema{}
+}
+
+// addEnum restricts the schema of a string field that looks like an enum
+// to the values seen, and null when it was seen. The enum makes its length
+// constraints redundant.
+func addEnum(schema *jsonSchema, field *FieldInfo) {
+	values := field.stats.enumValues()
+	if field.Type != "string" || values == nil {
+		return
+	}
+	for _, v := range values {
+		schema.Enum = append(schema.Enum, v)
+	}
+	if field.hasNull {
+		schema.Enum = append(schema.Enum, nil)
+	}
+	schema.MinLength, schema.MaxLength = nil, nil
+}
+
+func addStatsConstraints(schema *jsonSchema, s *fieldStats) {
+	if s.numbers > 0 {
+		min, max := s.min, s.max
+		schema.Minimum, schema.Maximum = &min, &max
+	}
+	if s.strings > 0 {
+		minLen, maxLen := s.minLen, s.maxLen
+		schema.MinLength, schema.MaxLength = &minLen, &maxLen
+	}
+	if s.arrays > 0 {
+		minItems, maxItems := s.minItems, s.maxItems
+		schema.MinItems, schema.MaxItems = &minItems, &maxItems
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWriteJSONSchema(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{
+			"name":   "Alice",
+			"scores": []interface{}{1.0, 2.0},
+			"tags": []interface{}{
+				map[string]interface{}{"id": 1.0},
+			},
+		},
+		map[string]interface{}{"name": "Bob", "avatar": nil},
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONSchema(&buf, analyzeJSON(data), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	expected := map[string]interface{}{
+		"$schema": jsonSchemaDialect,
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"avatar": map[string]interface{}{"type": "null"},
+			"name":   map[string]interface{}{"type": "string"},
+			"scores": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "number"},
+			},
+			"tags": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"id": map[string]interface{}{"type": "number"}},
+					"required":   []interface{}{"id"},
+				},
+			},
+		},
+		"required": []interface{}{"name"},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("unexpected schema:\n%s", buf.String())
+	}
+}
+
+func TestWriteJSONSchemaStats(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{"age": 20.0, "name": "Al", "tags": []interface{}{"a"}, "nick": nil, "status": "open"},
+		map[string]interface{}{"age": 40.0, "name": "Alice", "tags": []interface{}{}, "nick": "x", "status": "open"},
+		map[string]interface{}{"age": 30.0, "name": "Bob", "tags": []interface{}{}, "nick": "x", "status": "open"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONSchema(&buf, analyzeJSON(data), &renderOptions{stats: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Properties map[string]map[string]interface{}
+		Required   []string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		field, keyword string
+		expected       interface{}
+	}{
+		{"age", "minimum", 20.0},
+		{"age", "maximum", 40.0},
+		{"name", "minLength", 2.0},
+		{"name", "maxLength", 5.0},
+		{"tags", "minItems", 0.0},
+		{"tags", "maxItems", 1.0},
+		{"nick", "type", []interface{}{"string", "null"}},
+		{"nick", "enum", []interface{}{"x", nil}},
+		{"nick", "maxLength", nil},
+		{"status", "enum", []interface{}{"open"}},
+		{"name", "enum", nil},
+	}
+	for _, c := range checks {
+		if got := schema.Properties[c.field][c.keyword]; !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s.%s = %v; want %v", c.field, c.keyword, got, c.expected)
+		}
+	}
+	// nick is nullable but present in every object, so still required.
+	if expected := []string{"age", "name", "nick", "status", "tags"}; !reflect.DeepEqual(schema.Required, expected) {
+		t.Errorf("required = %v; want %v", schema.Required, expected)
+	}
+}
@@ -0,0 +1,232 @@
+// Package jsonshapetest asserts the shape of JSON documents in Go tests, so
+// that a service can check the structure of its handler responses without
+// pinning their values.
+//
+// A shape lists one line per field path, in the path notation of json-shape:
+// dots for nesting and [] for the elements of an array. Each line holds the
+// types seen at the path and whether the field was missing from some of the
+// objects holding it or was null:
+//
+//	id: number
+//	items: array
+//	items[]: object
+//	items[].note: string (optional, nullable)
+//	items[].sku: string
+//
+// Shape files are written rather than edited by hand: run the tests with
+// JSONSHAPE_UPDATE=1 set to record the shape of the documents they check,
+// and review the files like any other change.
+package jsonshapetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// updateEnv names the environment variable that makes RequireShape record
+// shapes instead of checking them.
+const updateEnv = "JSONSHAPE_UPDATE"
+
+// RequireShape fails the test, printing a line diff, unless the JSON
+// document gotJSON has the shape recorded in wantShapeFile. With
+// JSONSHAPE_UPDATE=1 set, it writes the shape of gotJSON to wantShapeFile
+// instead.
+func RequireShape(t testing.TB, gotJSON []byte, wantShapeFile string) {
+	t.Helper()
+	got, err := Shape(gotJSON)
+	if err != nil {
+		t.Fatalf("jsonshapetest: %v", err)
+	}
+	if os.Getenv(updateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(wantShapeFile), 0o755); err != nil {
+			t.Fatalf("jsonshapetest: %v", err)
+		}
+		if err := os.WriteFile(wantShapeFile, []byte(got), 0o644); err != nil {
+			t.Fatalf("jsonshapetest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(wantShapeFile)
+	if os.IsNotExist(err) {
+		t.Fatalf("jsonshapetest: %s does not exist; run the test with %s=1 to record it", wantShapeFile, updateEnv)
+	}
+	if err != nil {
+		t.Fatalf("jsonshapetest: %v", err)
+	}
+	if diff := Diff(string(want), got); diff != "" {
+		t.Fatalf("shape does not match %s (-want +got):\n%s", wantShapeFile, diff)
+	}
+}
+
+// Shape returns the shape of a JSON document in the notation of shape files.
+func Shape(data []byte) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("not JSON: %v", err)
+	}
+	root := newNode()
+	root.observe(doc)
+
+	var b strings.Builder
+	if _, ok := doc.(map[string]interface{}); ok {
+		root.writeChildren(&b, "")
+	} else {
+		root.write(&b, "(root)", "", 1)
+	}
+	return b.String(), nil
+}
+
+// node gathers the values seen at one path.
+type node struct {
+	types    map[string]bool
+	count    int // values seen, nulls included
+	nulls    int
+	objects  int // objects among the values, which children are counted against
+	children map[string]*node
+	elements *node // the elements of arrays among the values
+}
+
+func newNode() *node {
+	return &node{types: make(map[string]bool), children: make(map[string]*node)}
+}
+
+func (n *node) observe(value interface{}) {
+	n.count++
+	switch v := value.(type) {
+	case nil:
+		n.nulls++
+	case bool:
+		n.types["boolean"] = true
+	case float64:
+		n.types["number"] = true
+	case string:
+		n.types["string"] = true
+	case []interface{}:
+		n.types["array"] = true
+		if n.elements == nil && len(v) > 0 {
+			n.elements = newNode()
+		}
+		for _, item := range v {
+			n.elements.observe(item)
+		}
+	case map[string]interface{}:
+		n.types["object"] = true
+		n.objects++
+		for key, item := range v {
+			child := n.children[key]
+			if child == nil {
+				child = newNode()
+				n.children[key] = child
+			}
+			child.observe(item)
+		}
+	}
+}
+
+// write writes the line of n at path, which is optional when n was seen in
+// fewer than parentObjects objects, followed by the lines of its elements
+// and children.
+func (n *node) write(b *strings.Builder, path, childPrefix string, parentObjects int) {
+	types := make([]string, 0, len(n.types))
+	for typ := range n.types {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	typ := strings.Join(types, "|")
+	if typ == "" {
+		typ = "null"
+	}
+
+	var markers []string
+	if n.count < parentObjects {
+		markers = append(markers, "optional")
+	}
+	if n.nulls > 0 && n.nulls < n.count {
+		markers = append(markers, "nullable")
+	}
+	b.WriteString(path + ": " + typ)
+	if len(markers) > 0 {
+		b.WriteString(" (" + strings.Join(markers, ", ") + ")")
+	}
+	b.WriteByte('\n')
+
+	if n.elements != nil {
+		n.elements.write(b, childPrefix+"[]", childPrefix+"[]", 0)
+	}
+	n.writeChildren(b, childPrefix)
+}
+
+func (n *node) writeChildren(b *strings.Builder, prefix string) {
+	keys := make([]string, 0, len(n.children))
+	for key := range n.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := childPath(prefix, key)
+		n.children[key].write(b, path, path, n.objects)
+	}
+}
+
+// childPath appends key to the path of its parent, quoting keys that
+// would be ambiguous in a path: `a["b.c"]`.
+func childPath(parent, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\":\n") || strings.TrimSpace(key) != key {
+		return parent + "[" + strconv.Quote(key) + "]"
+	}
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// Diff returns a line diff of want and got, each line prefixed with "-"
+// when only want has it, "+" when only got has it and " " otherwise, or ""
+// when they are equal.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			out.WriteString("+" + b[j] + "\n")
+			j++
+		default:
+			out.WriteString("-" + a[i] + "\n")
+			i++
+		}
+	}
+	return out.String()
+}
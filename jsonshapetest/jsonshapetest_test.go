@@ -0,0 +1,126 @@
+package jsonshapetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestShape(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"object",
+			`{"id": 1, "name": "Ada", "tags": ["a", "b"], "address": {"city": "Ghent", "zip": null}}`,
+			"address: object\naddress.city: string\naddress.zip: null\nid: number\nname: string\ntags: array\ntags[]: string\n",
+		},
+		{
+			"array of objects",
+			`[{"id": 1, "note": "x"}, {"id": 2, "note": null}, {"id": "3"}]`,
+			"(root): array\n[]: object\n[].id: number|string\n[].note: string (optional, nullable)\n",
+		},
+		{
+			"nested arrays and empty arrays",
+			`{"matrix": [[1, 2], []], "none": [], "items": [{"sku": "a"}, {"sku": "b", "qty": 2}]}`,
+			"items: array\nitems[]: object\nitems[].qty: number (optional)\nitems[].sku: string\nmatrix: array\nmatrix[]: array\nmatrix[][]: number\nnone: array\n",
+		},
+		{
+			"quoted keys",
+			`{"a.b": 1, "c": {"": true, " d": "x"}}`,
+			"[\"a.b\"]: number\nc: object\nc[\"\"]: boolean\nc[\" d\"]: string\n",
+		},
+		{
+			"scalar",
+			`42`,
+			"(root): number\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Shape([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expected {
+				t.Errorf("Shape() =\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := Shape([]byte(`{"a":`)); err == nil {
+		t.Error("Shape() of invalid JSON should fail")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	want := "id: number\nname: string\ntags: array\n"
+	got := "id: string\nname: string\ntags: array\ntags[]: string\n"
+	expected := "-id: number\n+id: string\n name: string\n tags: array\n+tags[]: string\n"
+	if diff := Diff(want, got); diff != expected {
+		t.Errorf("Diff() =\n%s\nwant:\n%s", diff, expected)
+	}
+	if diff := Diff(want, want); diff != "" {
+		t.Errorf("Diff() of equal shapes = %q; want \"\"", diff)
+	}
+}
+
+// fakeT records the failure of a helper instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failure string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// requireShape runs RequireShape and returns its failure, or "".
+func requireShape(gotJSON []byte, wantShapeFile string) string {
+	ft := &fakeT{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RequireShape(ft, gotJSON, wantShapeFile)
+	}()
+	<-done
+	return ft.failure
+}
+
+func TestRequireShape(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "testdata", "user.shape")
+	user := []byte(`{"id": 1, "name": "Ada"}`)
+
+	if failure := requireShape(user, file); !strings.Contains(failure, "JSONSHAPE_UPDATE=1") {
+		t.Errorf("missing shape file: failure = %q", failure)
+	}
+
+	t.Setenv(updateEnv, "1")
+	if failure := requireShape(user, file); failure != "" {
+		t.Fatalf("recording: %s", failure)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "id: number\nname: string\n" {
+		t.Errorf("recorded shape = %q", data)
+	}
+
+	t.Setenv(updateEnv, "")
+	if failure := requireShape([]byte(`{"id": 2, "name": "Bob"}`), file); failure != "" {
+		t.Errorf("same shape: failure = %q", failure)
+	}
+	expected := "shape does not match " + file + " (-want +got):\n-id: number\n-name: string\n+id: string\n"
+	if failure := requireShape([]byte(`{"id": "2"}`), file); failure != expected {
+		t.Errorf("changed shape: failure =\n%s\nwant:\n%s", failure, expected)
+	}
+	if failure := requireShape([]byte(`{"id":`), file); !strings.HasPrefix(failure, "jsonshapetest: not JSON") {
+		t.Errorf("invalid JSON: failure = %q", failure)
+	}
+}
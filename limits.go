@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// exitLimitExceeded is the exit status when a run goes over one of the
+// --max-* limits, so that pipelines can tell rejected inputs from errors.
+const exitLimitExceeded = 4
+
+// limitError reports that a run went over the limit set by flag.
+type limitError struct {
+	flag  string
+	limit int64
+	what  string // what went over, e.g. "input is larger than"
+}
+
+func (e *limitError) Error() string {
+	return fmt.Sprintf("%s --%s=%d", e.what, e.flag, e.limit)
+}
+
+// exitStatus returns the exit status for a failed run.
+func exitStatus(err error) int {
+	var lerr *limitError
+	if errors.As(err, &lerr) {
+		return exitLimitExceeded
+	}
+	return 1
+}
+
+// inputBudget is the number of bytes all sources of a run may still supply.
+type inputBudget struct {
+	limit    int64
+	used     int64
+	exceeded bool
+}
+
+func (b *inputBudget) err() error {
+	return &limitError{flag: "max-input-bytes", limit: b.limit, what: "input is larger than"}
+}
+
+// budgetReader reads from r while b allows it. Decoders may turn the error
+// it returns into one of their own, so readers of the input check
+// b.exceeded instead.
+type budgetReader struct {
+	r io.Reader
+	b *inputBudget
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	if r.b.exceeded {
+		return 0, r.b.err()
+	}
+	// Read one byte past the limit to tell an input of exactly the limit
+	// from a larger one.
+	if room := r.b.limit - r.b.used + 1; int64(len(p)) > room {
+		p = p[:room]
+	}
+	n, err := r.r.Read(p)
+	r.b.used += int64(n)
+	if r.b.used > r.b.limit {
+		r.b.exceeded = true
+		return n - int(r.b.used-r.b.limit), r.b.err()
+	}
+	return n, err
+}
+
+// docBudget is the number of documents a run may still analyze.
+type docBudget struct {
+	limit    int
+	used     int
+	exceeded bool
+}
+
+// take counts n more documents, failing once they are more than the limit,
+// if there is one.
+func (b *docBudget) take(n int) error {
+	b.used += n
+	if b.limit > 0 && b.used > b.limit {
+		b.exceeded = true
+		return &limitError{flag: "max-docs", limit: int64(b.limit), what: "input has more documents than"}
+	}
+	return nil
+}
+
+// budgetDecoder decodes documents from dec while b allows it. The elements
+// of a document that is an array count as documents, as they are analyzed
+// as such; they are counted once the array is decoded, so that only the
+// input budget bounds the memory a single array takes.
+type budgetDecoder struct {
+	dec valueDecoder
+	b   *docBudget
+}
+
+func (d *budgetDecoder) Decode() (interface{}, error) {
+	doc, err := d.dec.Decode()
+	if err != nil {
+		return doc, err
+	}
+	n := 1
+	if items, ok := doc.([]interface{}); ok {
+		n = len(items)
+	}
+	if err := d.b.take(n); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// cappedWriter collects up to limit bytes of output, so that output over
+// the limit is rejected whole instead of written in part.
+type cappedWriter struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if w.exceeded || int64(w.buf.Len()+len(p)) > w.limit {
+		w.exceeded = true
+		return 0, w.err()
+	}
+	return w.buf.Write(p)
+}
+
+func (w *cappedWriter) err() error {
+	return &limitError{flag: "max-output-bytes", limit: w.limit, what: "output is larger than"}
+}
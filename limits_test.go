@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadSourcesLimits(t *testing.T) {
+	// 16 bytes and 3 documents in all.
+	sources := []source{
+		{name: "a.ndjson", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("{\"a\":1}\n")), nil
+		}},
+		{name: "b.json", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("[1,2]\n\n\n")), nil
+		}},
+	}
+
+	tests := []struct {
+		name string
+		opts decodeOptions
+		want string // error, or "" to succeed
+	}{
+		{"no limits", decodeOptions{}, ""},
+		{"bytes at limit", decodeOptions{maxInputBytes: 16}, ""},
+		{"bytes over limit", decodeOptions{maxInputBytes: 15}, "b.json: input is larger than --max-input-bytes=15"},
+		{"bytes over limit in first input", decodeOptions{maxInputBytes: 4}, "a.ndjson: input is larger than --max-input-bytes=4"},
+		{"docs at limit", decodeOptions{maxDocs: 3}, ""},
+		{"array elements count", decodeOptions{maxDocs: 2}, "b.json: document 1: input has more documents than --max-docs=2"},
+		{"not skipped", decodeOptions{maxDocs: 2, skipInvalid: true}, "b.json: document 1: input has more documents than --max-docs=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readSources(sources, "", &tt.opts, io.Discard)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("readSources() error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("readSources() error = %v, want %q", err, tt.want)
+			}
+			if got := exitStatus(err); got != exitLimitExceeded {
+				t.Errorf("exitStatus() = %d, want %d", got, exitLimitExceeded)
+			}
+		})
+	}
+}
+
+func TestReadSourcesSpreadsheetLimit(t *testing.T) {
+	// The sheet compresses to far less than it unzips to.
+	sheet := `<worksheet><sheetData>` + strings.Repeat(`<row><c t="str"><v>padding</v></c></row>`, 1000) + `</sheetData></worksheet>`
+	data := zipArchive(t, map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml":   sheet,
+	})
+	sources := []source{{name: "big.xlsx", open: func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}}}
+
+	limit := int64(len(data) + 1)
+	_, err := readSources(sources, "", &decodeOptions{maxInputBytes: limit}, io.Discard)
+	want := fmt.Sprintf("big.xlsx: document 1: unzipped spreadsheet is larger than --max-input-bytes=%d", limit)
+	if err == nil || err.Error() != want {
+		t.Fatalf("readSources() error = %v, want %q", err, want)
+	}
+	if got := exitStatus(err); got != exitLimitExceeded {
+		t.Errorf("exitStatus() = %d, want %d", got, exitLimitExceeded)
+	}
+	if _, err := readSources(sources, "", &decodeOptions{maxInputBytes: int64(len(sheet) + 1000)}, io.Discard); err != nil {
+		t.Errorf("readSources() under the limit: %v", err)
+	}
+}
+
+func TestReadSourcesSpreadsheetExpansionLimits(t *testing.T) {
+	// A few hundred bytes of XML repeat a row of 1000 cells 60000 times.
+	content := `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet><table:table table:name="Repeated">
+<table:table-row><table:table-cell office:value-type="string"><text:p>name</text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="60000"><table:table-cell table:number-columns-repeated="1000" office:value-type="float" office:value="1"/></table:table-row>
+</table:table></office:spreadsheet></office:body></office:document-content>`
+	data := zipArchive(t, map[string]string{"content.xml": content})
+	sources := []source{{name: "repeated.ods", open: func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}}}
+
+	tests := []struct {
+		name string
+		opts decodeOptions
+		want string
+	}{
+		{"rows", decodeOptions{maxDocs: 1000}, "repeated.ods: document 1: spreadsheet: content.xml: input has more documents than --max-docs=1000"},
+		{"cells", decodeOptions{maxInputBytes: 100000}, "repeated.ods: document 1: spreadsheet: content.xml: expanded spreadsheet is larger than --max-input-bytes=100000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readSources(sources, "", &tt.opts, io.Discard)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("readSources() error = %v, want %q", err, tt.want)
+			}
+			if got := exitStatus(err); got != exitLimitExceeded {
+				t.Errorf("exitStatus() = %d, want %d", got, exitLimitExceeded)
+			}
+		})
+	}
+}
+
+func TestCappedWriter(t *testing.T) {
+	w := &cappedWriter{limit: 8}
+	if _, err := io.WriteString(w, "1234"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "5678"); err != nil {
+		t.Fatal(err)
+	}
+	if w.exceeded || w.buf.String() != "12345678" {
+		t.Fatalf("at the limit: exceeded %v, output %q", w.exceeded, w.buf.String())
+	}
+	_, err := io.WriteString(w, "9")
+	var lerr *limitError
+	if !w.exceeded || !errors.As(err, &lerr) || lerr.flag != "max-output-bytes" {
+		t.Fatalf("over the limit: exceeded %v, error %v", w.exceeded, err)
+	}
+	if got := exitStatus(errors.New("other")); got != 1 {
+		t.Errorf("exitStatus() of other errors = %d, want 1", got)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// namingConventions are the conventions namingConvention recognizes.
+var namingConventions = []string{"camelCase", "PascalCase", "snake_case", "SCREAMING_SNAKE_CASE", "kebab-case"}
+
+// lintKeys reports problematic key names in fields: sibling keys that differ
+// only by case, keys containing whitespace or non-ASCII characters, and keys
+// that do not follow the naming convention, which is naming when set and
+// otherwise the one used by most of the document.
+func lintKeys(fields map[string]*FieldInfo, naming string) []string {
+	var warnings []string
+	conventions := make(map[string][]string)
+	lintLevel(fields, "", &warnings, conventions)
+
+	dominant, want := naming, "the naming convention is "+naming
+	if naming == "" {
+		for name, paths := range conventions {
+			if dominant == "" || len(paths) > len(conventions[dominant]) ||
+				(len(paths) == len(conventions[dominant]) && name < dominant) {
+				dominant = name
+			}
+		}
+		want = "most keys are " + dominant
+	}
+	if dominant == "" {
+		return warnings
+	}
+
+	names := make([]string, 0, len(conventions))
+	for name := range conventions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == dominant {
+			continue
+		}
+		for _, path := range conventions[name] {
+			warnings = append(warnings, fmt.Sprintf("%s: key is %s but %s", path, name, want))
+		}
+	}
+	return warnings
+}
+
+func lintLevel(fields map[string]*FieldInfo, parent string, warnings *[]string, conventions map[string][]string) {
+	keys := sortedKeys(fields)
+
+	byFold := make(map[string][]string)
+	for _, key := range keys {
+		folded := strings.ToLower(key)
+		byFold[folded] = append(byFold[folded], key)
+	}
+
+	for _, key := range keys {
+		path := fieldPath(parent, key)
+
+		if group := byFold[strings.ToLower(key)]; len(group) > 1 && group[0] == key {
+			*warnings = append(*warnings, fmt.Sprintf("%s: keys differ only by case: %s", path, strings.Join(group, ", ")))
+		}
+
+		hasSpace, nonASCII := false, false
+		for _, r := range key {
+			if unicode.IsSpace(r) {
+				hasSpace = true
+			}
+			if r > unicode.MaxASCII {
+				nonASCII = true
+			}
+		}
+		if hasSpace {
+			*warnings = append(*warnings, fmt.Sprintf("%s: key contains whitespace", path))
+		}
+		if nonASCII {
+			*warnings = append(*warnings, fmt.Sprintf("%s: key contains non-ASCII characters", path))
+		}
+		if !hasSpace && !nonASCII {
+			if name := namingConvention(key); name != "" {
+				conventions[name] = append(conventions[name], path)
+			}
+		}
+
+		field := fields[key]
+		if len(field.Children) > 0 {
+			lintLevel(field.Children, childPath(path, field), warnings, conventions)
+		}
+	}
+}
+
+// namingConvention classifies key, returning "" for keys that mix several
+// styles and for single lowercase words, which are valid in camelCase and
+// snake_case alike.
+func namingConvention(key string) string {
+	if key == "" || key[0] == '_' || key[0] == '-' {
+		return ""
+	}
+
+	hasLower, hasUpper, hasUnderscore, hasDash := false, false, false, false
+	for _, r := range key {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case r == '_':
+			hasUnderscore = true
+		case r == '-':
+			hasDash = true
+		case unicode.IsDigit(r):
+		default:
+			return ""
+		}
+	}
+
+	switch {
+	case hasUnderscore && hasDash:
+		return ""
+	case hasUnderscore && !hasUpper:
+		return "snake_case"
+	case hasUnderscore && !hasLower:
+		return "SCREAMING_SNAKE_CASE"
+	case hasDash && !hasUpper:
+		return "kebab-case"
+	case hasUnderscore || hasDash || !hasUpper || !hasLower:
+		return ""
+	case unicode.IsUpper(rune(key[0])):
+		return "PascalCase"
+	default:
+		return "camelCase"
+	}
+}
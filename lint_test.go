@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamingConvention(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"userId", "camelCase"},
+		{"UserId", "PascalCase"},
+		{"user_id", "snake_case"},
+		{"USER_ID", "SCREAMING_SNAKE_CASE"},
+		{"user-id", "kebab-case"},
+		{"name", ""},
+		{"ID", ""},
+		{"_id", ""},
+		{"user_Id", ""},
+	}
+
+	for _, tt := range tests {
+		if result := namingConvention(tt.input); result != tt.expected {
+			t.Errorf("namingConvention(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestLintKeys(t *testing.T) {
+	data := map[string]interface{}{
+		"userId":    1.0,
+		"userID":    2.0,
+		"firstName": "Alice",
+		"lastName":  "Martin",
+		"last_seen": "today",
+		"tags": []interface{}{
+			map[string]interface{}{"display name": "x", "café": true},
+		},
+	}
+
+	warnings := lintKeys(analyzeJSON(data), "")
+	output := strings.Join(warnings, "\n")
+
+	expected := []string{
+		"userID: keys differ only by case: userID, userId",
+		`tags[]["display name"]: key contains whitespace`,
+		"tags[].café: key contains non-ASCII characters",
+		"last_seen: key is snake_case but most keys are camelCase",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing warning %q\nGot:\n%s", want, output)
+		}
+	}
+	if len(warnings) != len(expected) {
+		t.Errorf("expected %d warnings, got %d:\n%s", len(expected), len(warnings), output)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// logDecoder reads newline-delimited log envelopes, such as the entries of
+// journalctl -o json or of Docker's json-file logging driver, and decodes
+// the application messages inside them. Messages that are not JSON objects
+// or arrays, like plain-text log lines, are ignored.
+type logDecoder struct {
+	envelopes valueDecoder
+	// message returns an envelope's message and whether it is complete;
+	// incomplete messages are joined with the rest of their stream.
+	message func(envelope map[string]interface{}) (text, stream string, complete bool)
+	partial map[string]string
+	order   *keyOrder // of the messages, not the envelopes
+}
+
+func newJournalDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return newLogDecoder(r, opts, journalMessage, nil)
+}
+
+func newDockerLogDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return newLogDecoder(r, opts, dockerMessage, make(map[string]string))
+}
+
+func newLogDecoder(r io.Reader, opts *decodeOptions, message func(map[string]interface{}) (string, string, bool), partial map[string]string) valueDecoder {
+	d := &logDecoder{message: message, partial: partial}
+	if opts != nil {
+		envelopeOpts := *opts
+		envelopeOpts.keyOrder = nil
+		d.order, opts = opts.keyOrder, &envelopeOpts
+	}
+	d.envelopes = newJSONDecoder(r, opts)
+	return d
+}
+
+func (d *logDecoder) Decode() (interface{}, error) {
+	for {
+		v, err := d.envelopes.Decode()
+		if err != nil {
+			return nil, err
+		}
+		envelope, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		text, stream, complete := d.message(envelope)
+		if !complete {
+			d.partial[stream] += text
+			continue
+		}
+		if d.partial != nil {
+			text = d.partial[stream] + text
+			delete(d.partial, stream)
+		}
+
+		text = strings.TrimSpace(text)
+		if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
+			continue
+		}
+		var msg interface{}
+		if err := json.Unmarshal([]byte(text), &msg); err != nil {
+			continue
+		}
+		return msg, d.order.scan([]byte(text))
+	}
+}
+
+// journalMessage returns the MESSAGE field of a journal entry. Messages that
+// are not valid UTF-8 are exported as arrays of byte values.
+func journalMessage(entry map[string]interface{}) (string, string, bool) {
+	switch msg := entry["MESSAGE"].(type) {
+	case string:
+		return msg, "", true
+	case []interface{}:
+		var buf bytes.Buffer
+		for _, b := range msg {
+			if n, ok := b.(float64); ok {
+				buf.WriteByte(byte(n))
+			}
+		}
+		return buf.String(), "", true
+	}
+	return "", "", true
+}
+
+// dockerMessage returns the log field of a json-file entry. Docker splits
+// lines longer than 16 KiB across entries; only the last ends in a newline.
+func dockerMessage(entry map[string]interface{}) (string, string, bool) {
+	text, _ := entry["log"].(string)
+	stream, _ := entry["stream"].(string)
+	return text, stream, strings.HasSuffix(text, "\n")
+}
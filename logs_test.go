@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJournalDecoder(t *testing.T) {
+	input := strings.Join([]string{
+		`{"__CURSOR":"s=1","_PID":"42","MESSAGE":"{\"level\":\"info\",\"msg\":\"started\"}"}`,
+		`{"__CURSOR":"s=2","_PID":"42","MESSAGE":"plain text line"}`,
+		`{"__CURSOR":"s=3","_PID":"42","MESSAGE":[123,34,108,101,118,101,108,34,58,34,101,114,114,111,114,34,125]}`,
+		`{"__CURSOR":"s=4","_PID":"42","MESSAGE":null}`,
+		`{"__CURSOR":"s=5","_PID":"42","MESSAGE":"{not json"}`,
+	}, "\n")
+
+	docs, err := decodeAll(newJournalDecoder(strings.NewReader(input), nil))
+	if err != nil {
+		t.Fatalf("decodeAll() error: %v", err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"level": "info", "msg": "started"},
+		map[string]interface{}{"level": "error"},
+	}
+	if !reflect.DeepEqual(docs, expected) {
+		t.Errorf("decodeAll() = %#v; want %#v", docs, expected)
+	}
+}
+
+func TestDockerLogDecoder(t *testing.T) {
+	input := strings.Join([]string{
+		`{"log":"{\"user\":{\"id\":1},","stream":"stdout","time":"2024-01-01T00:00:00Z"}`,
+		`{"log":"listening on :8080\n","stream":"stderr","time":"2024-01-01T00:00:00Z"}`,
+		`{"log":"\"ok\":true}\n","stream":"stdout","time":"2024-01-01T00:00:01Z"}`,
+		`{"log":"[1,2]\n","stream":"stderr","time":"2024-01-01T00:00:02Z"}`,
+	}, "\n")
+
+	docs, err := decodeAll(newDockerLogDecoder(strings.NewReader(input), nil))
+	if err != nil {
+		t.Fatalf("decodeAll() error: %v", err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"user": map[string]interface{}{"id": 1.0}, "ok": true},
+		[]interface{}{1.0, 2.0},
+	}
+	if !reflect.DeepEqual(docs, expected) {
+		t.Errorf("decodeAll() = %#v; want %#v", docs, expected)
+	}
+
+	if got := inputForName("/var/lib/docker/containers/abc/abc-json.log"); got != "docker" {
+		t.Errorf("inputForName() = %q; want docker", got)
+	}
+}
+
+func TestLogDecoderKeyOrder(t *testing.T) {
+	order := newKeyOrder()
+	input := `{"log":"{\"level\":\"info\",\"msg\":\"hi\"}\n","stream":"stdout","time":"2024-01-01T00:00:00Z"}` + "\n"
+	if _, err := decodeAll(newDockerLogDecoder(strings.NewReader(input), &decodeOptions{keyOrder: order})); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]int{"": {"level": 0, "msg": 1}}
+	if !reflect.DeepEqual(order.positions, expected) {
+		t.Errorf("positions = %v, want only the message keys %v", order.positions, expected)
+	}
+}
@@ -1,73 +1,151 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 )
 
 type FieldInfo struct {
 	Type     string
-	Optional bool
+	Optional bool // missing from some of the objects that could hold it
+	Nullable bool // seen with a null value
+	// Assumed is set when the input is a single object that alone could
+	// hold the field, so that Optional is a policy rather than an
+	// observation; see assumeOptionality.
+	Assumed  bool
 	Children map[string]*FieldInfo
 	count    int
+	objects  int // objects among the values, counting array elements
 	hasNull  bool
+	isArray  bool
+	example  interface{}
+	stats    fieldStats
+	rank     int            // position among siblings in output order; see sortFields
+	history  []typeDecision // how Type came to be; see noteType
+}
+
+// analyzer infers the shape of documents as they are added, keeping the
+// number of the top-level document being read so that type decisions can
+// name it. Only the shape is kept, not the documents.
+type analyzer struct {
+	fields       map[string]*FieldInfo
+	doc          int
+	docs         int  // documents added
+	arrays       int  // documents added that are arrays
+	values       int  // documents added, counting array elements
+	objects      int  // objects among the values
+	singleObject bool // the only document added is an object
+}
+
+func newAnalyzer() *analyzer {
+	return &analyzer{fields: make(map[string]*FieldInfo)}
 }
 
 func analyzeJSON(data interface{}) map[string]*FieldInfo {
-	result := make(map[string]*FieldInfo)
-	total := 0
+	a := newAnalyzer()
+	a.add(data)
+	return a.shape()
+}
 
-	switch v := data.(type) {
-	case map[string]interface{}:
-		total = 1
-		for key, value := range v {
-			mergeField(result, key, value)
-		}
-	case []interface{}:
-		for _, item := range v {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				total++
-				for key, value := range itemMap {
-					mergeField(result, key, value)
-				}
+// add merges the fields of a top-level document into the shape. The
+// elements of a document that is an array are added as documents, as
+// mergeDocuments flattens them, and numbered from 1 across documents.
+func (a *analyzer) add(doc interface{}) {
+	a.docs++
+	items, isArray := doc.([]interface{})
+	if isArray {
+		a.arrays++
+	} else {
+		items = []interface{}{doc}
+	}
+	_, isObject := doc.(map[string]interface{})
+	a.singleObject = a.docs == 1 && isObject
+
+	for _, item := range items {
+		a.values++
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			a.objects++
+			a.doc = a.values
+			for key, value := range itemMap {
+				a.mergeField(a.fields, key, value)
 			}
 		}
 	}
+}
+
+// shape returns the fields of the documents added, with the optionality of
+// those missing from some objects.
+func (a *analyzer) shape() map[string]*FieldInfo {
+	finalizeOptionality(a.fields, a.objects)
+	return a.fields
+}
+
+// single reports whether the input was a single object, whose optionality
+// one sample cannot show; see assumeOptionality.
+func (a *analyzer) single() bool {
+	return a.singleObject
+}
+
+// list reports whether every document added was an array, so that the
+// input is a list of the objects the shape describes.
+func (a *analyzer) list() bool {
+	return a.docs > 0 && a.arrays == a.docs
+}
 
-	finalizeOptionality(result, total)
+// analyze infers the fields of a nested object.
+func (a *analyzer) analyze(obj map[string]interface{}) map[string]*FieldInfo {
+	result := make(map[string]*FieldInfo)
+	for key, value := range obj {
+		a.mergeField(result, key, value)
+	}
+	finalizeOptionality(result, 1)
 	return result
 }
 
 func finalizeOptionality(fields map[string]*FieldInfo, parentCount int) {
 	for _, field := range fields {
-		if field.count < parentCount || field.hasNull {
-			field.Optional = true
-		}
+		field.Optional = field.count < parentCount
+		field.Nullable = field.hasNull
 		if len(field.Children) > 0 {
-			finalizeOptionality(field.Children, field.count)
+			finalizeOptionality(field.Children, field.objects)
 		}
 	}
 }
 
-func mergeField(fields map[string]*FieldInfo, key string, value interface{}) {
+func (a *analyzer) mergeField(fields map[string]*FieldInfo, key string, value interface{}) {
 	// If value is already a *FieldInfo, we are merging two trees
 	if newInfo, ok := value.(*FieldInfo); ok {
 		if existing, ok := fields[key]; ok {
+			before := displayType(existing)
 			if (existing.Type == "" || existing.Type == "unknown" || existing.Type == "array<unknown>") &&
 				(newInfo.Type != "" && newInfo.Type != "unknown" && newInfo.Type != "array<unknown>") {
 				existing.Type = newInfo.Type
 			}
 			existing.count += newInfo.count
+			existing.objects += newInfo.objects
 			if newInfo.hasNull {
 				existing.hasNull = true
 			}
+			if newInfo.isArray {
+				existing.isArray = true
+			}
+			if existing.example == nil {
+				existing.example = newInfo.example
+			}
+			existing.stats.merge(&newInfo.stats)
+			existing.noteType(a.doc, before, displayType(newInfo))
+			for _, d := range newInfo.history {
+				// Types the new tree saw and set aside within the document.
+				existing.noteType(d.doc, displayType(existing), d.seenType())
+			}
 			for k, v := range newInfo.Children {
-				mergeField(existing.Children, k, v)
+				a.mergeField(existing.Children, k, v)
 			}
 			return
 		}
@@ -76,11 +154,17 @@ func mergeField(fields map[string]*FieldInfo, key string, value interface{}) {
 	}
 
 	if existing, ok := fields[key]; ok {
+		before := displayType(existing)
 		existing.count++
 		if value == nil {
 			existing.hasNull = true
 		}
 
+		if existing.example == nil {
+			existing.example = exampleValue(value)
+		}
+		existing.stats.observe(value)
+
 		// Upgrade type if currently unknown
 		if (existing.Type == "unknown" || existing.Type == "array<unknown>") && value != nil {
 			newType := getType(value)
@@ -91,21 +175,25 @@ func mergeField(fields map[string]*FieldInfo, key string, value interface{}) {
 
 		// If we find children in a subsequent object, merge them
 		if nestedMap, ok := value.(map[string]interface{}); ok {
-			childFields := analyzeJSON(nestedMap)
+			existing.objects++
+			childFields := a.analyze(nestedMap)
 			for ck, cv := range childFields {
-				mergeField(existing.Children, ck, cv)
+				a.mergeField(existing.Children, ck, cv)
 			}
 		} else if nestedArray, ok := value.([]interface{}); ok {
 			for _, item := range nestedArray {
 				if itemMap, ok := item.(map[string]interface{}); ok {
-					arrayChildren := analyzeJSON(itemMap)
+					existing.objects++
+					arrayChildren := a.analyze(itemMap)
 					for ck, cv := range arrayChildren {
-						mergeField(existing.Children, ck, cv)
+						a.mergeField(existing.Children, ck, cv)
 					}
 					existing.Type = ""
+					existing.isArray = true
 				}
 			}
 		}
+		existing.noteType(a.doc, before, valueType(value))
 		return
 	}
 
@@ -115,21 +203,26 @@ func mergeField(fields map[string]*FieldInfo, key string, value interface{}) {
 		Children: make(map[string]*FieldInfo),
 		count:    1,
 		hasNull:  value == nil,
+		example:  exampleValue(value),
 	}
+	fieldInfo.stats.observe(value)
 
 	if nestedMap, ok := value.(map[string]interface{}); ok {
-		fieldInfo.Children = analyzeJSON(nestedMap)
+		fieldInfo.Children = a.analyze(nestedMap)
 		fieldInfo.Type = ""
+		fieldInfo.objects = 1
 	} else if nestedArray, ok := value.([]interface{}); ok {
 		if len(nestedArray) > 0 {
 			// Merge all objects in the array
 			for _, item := range nestedArray {
 				if itemMap, ok := item.(map[string]interface{}); ok {
-					arrayChildren := analyzeJSON(itemMap)
+					fieldInfo.objects++
+					arrayChildren := a.analyze(itemMap)
 					for ck, cv := range arrayChildren {
-						mergeField(fieldInfo.Children, ck, cv)
+						a.mergeField(fieldInfo.Children, ck, cv)
 					}
 					fieldInfo.Type = ""
+					fieldInfo.isArray = true
 				}
 			}
 		} else {
@@ -137,6 +230,7 @@ func mergeField(fields map[string]*FieldInfo, key string, value interface{}) {
 		}
 	}
 
+	fieldInfo.history = []typeDecision{{doc: a.doc, to: displayType(fieldInfo)}}
 	fields[key] = fieldInfo
 }
 
@@ -167,17 +261,96 @@ func getType(value interface{}) string {
 	}
 }
 
-func printTree(fields map[string]*FieldInfo, prefix string, isRoot bool) {
-	if isRoot {
-		fmt.Println("root")
-		prefix = ""
+// exampleValue returns value if it can serve as a field's example: objects,
+// arrays of objects and nulls are described by the shape itself instead.
+func exampleValue(value interface{}) interface{} {
+	switch getType(value) {
+	case "object", "array", "unknown":
+		return nil
 	}
+	if s, ok := value.(string); ok {
+		return truncateExample(s)
+	}
+	return value
+}
 
+// displayType returns the type label of field, naming the objects and arrays
+// of objects that have an empty Type.
+func displayType(field *FieldInfo) string {
+	switch {
+	case field.Type != "":
+		return field.Type
+	case field.isArray:
+		return "array<object>"
+	default:
+		return "object"
+	}
+}
+
+// fieldMarkers returns the tree markers of field: " (optional)" when it can
+// be missing, " (nullable)" when it can be null, or " (optional, nullable)".
+// Fields whose optionality was assumed are marked "assumed required" or
+// "presence unknown" instead of optional.
+func fieldMarkers(field *FieldInfo) string {
+	var markers []string
+	switch {
+	case field.Assumed && field.Optional:
+		markers = append(markers, "presence unknown")
+	case field.Assumed:
+		markers = append(markers, "assumed required")
+	case field.Optional:
+		markers = append(markers, "optional")
+	}
+	if field.Nullable {
+		markers = append(markers, "nullable")
+	}
+	if len(markers) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(markers, ", ") + ")"
+}
+
+// parentCount returns the number of objects the presence of fields is
+// measured against: those of their parent, or for root fields, which have
+// none, the count of the most frequent field.
+func parentCount(parent *FieldInfo, fields map[string]*FieldInfo) int {
+	if parent != nil {
+		return parent.objects
+	}
+	count := 0
+	for _, field := range fields {
+		count = max(count, field.count)
+	}
+	return count
+}
+
+// sortedKeys returns the keys of fields in output order: by the rank given
+// by sortFields, then by name.
+func sortedKeys(fields map[string]*FieldInfo) []string {
 	keys := make([]string, 0, len(fields))
 	for k := range fields {
 		keys = append(keys, k)
 	}
-	sort.Strings(keys)
+	sort.Slice(keys, func(i, j int) bool {
+		if ri, rj := fields[keys[i]].rank, fields[keys[j]].rank; ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func printTree(fields map[string]*FieldInfo, prefix string, isRoot bool) {
+	writeTree(os.Stdout, fields, prefix, isRoot, &renderOptions{})
+}
+
+func writeTree(w io.Writer, fields map[string]*FieldInfo, prefix string, isRoot bool, opts *renderOptions) {
+	if isRoot {
+		fmt.Fprintln(w, "root")
+		prefix = ""
+	}
+
+	keys := sortedKeys(fields)
 
 	for i, key := range keys {
 		field := fields[key]
@@ -189,22 +362,21 @@ func printTree(fields map[string]*FieldInfo, prefix string, isRoot bool) {
 			connector = "└── "
 		}
 
+		statsStr := ""
+		if opts.stats {
+			if s := field.stats.String(); s != "" {
+				statsStr = " " + s
+			}
+		}
+
 		// Format the output
 		if len(field.Children) > 0 {
 			// Field has children (object or array of objects)
-			optionalStr := ""
-			if field.Optional {
-				optionalStr = " (optional)"
-			}
-			fmt.Printf("%s%s%s%s\n", prefix, connector, key, optionalStr)
+			fmt.Fprintf(w, "%s%s%s%s%s\n", prefix, connector, key, fieldMarkers(field), statsStr)
 		} else {
 			// Leaf field - show type
-			typeStr := field.Type
-			optionalStr := ""
-			if field.Optional {
-				optionalStr = " (optional)"
-			}
-			fmt.Printf("%s%s%s: %s%s\n", prefix, connector, key, typeStr, optionalStr)
+			typeStr := displayType(field)
+			fmt.Fprintf(w, "%s%s%s: %s%s%s\n", prefix, connector, key, typeStr, fieldMarkers(field), statsStr)
 		}
 
 		// Print children if any
@@ -216,44 +388,239 @@ func printTree(fields map[string]*FieldInfo, prefix string, isRoot bool) {
 				childPrefix += "│   "
 			}
 
-			printTree(field.Children, childPrefix, false)
+			writeTree(w, field.Children, childPrefix, false, opts)
 		}
 	}
 }
 
-func main() {
-	var reader io.Reader = os.Stdin
+// renderer writes fields in one output format.
+type renderer func(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error
+
+// renderOptions holds the settings shared by all output formats.
+type renderOptions struct {
+	stats    bool
+	budget   int
+	describe bool // draft field descriptions from the values seen
+	// skipFormats are the stringFormats descriptions do not name, as
+	// parsed by parseDetectors.
+	skipFormats uint
+
+	// openAPIPath and openAPIMethod turn the openapi format's component
+	// fragment into a description of one operation, which responds with an
+	// array of the objects when openAPIList is set, as for an input that
+	// was an array.
+	openAPIPath   string
+	openAPIMethod string
+	openAPIList   bool
+
+	// baseline is the shape the badge formats report drift from, as read
+	// by readBaseline, or nil; now is the verification time they show,
+	// the current time when zero.
+	baseline *shapeBaseline
+	now      time.Time
+
+	// warnings lists the paths the format could not express faithfully,
+	// along with the approximation chosen for each.
+	warnings []string
+}
+
+func (o *renderOptions) warn(path, note string) {
+	o.warnings = append(o.warnings, path+": "+note)
+}
+
+// formats maps each --format value to the function that renders the shape.
+var formats = map[string]renderer{
+	"tree": func(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+		writeTree(w, fields, "", true, opts)
+		return nil
+	},
+	"avro":       writeAvro,
+	"parquet":    writeParquet,
+	"markdown":   writeMarkdown,
+	"html":       writeHTML,
+	"jsonschema": writeJSONSchema,
+	"compact":    writeCompact,
+	"openapi":    writeOpenAPI,
+	"badge":      writeBadge,
+	"badge-svg":  writeBadgeSVG,
+	"zod":        writeZod,
+	"io-ts":      writeIOTS,
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// commands maps each subcommand to the function that runs it with the
+// remaining arguments.
+var commands = map[string]func(args []string) error{
+	"explain":  runExplain,
+	"find":     runFind,
+	"generate": runGenerate,
+	"proxy":    runProxy,
+	"validate": runValidate,
+}
+
+func commandName() string {
 	if len(os.Args) > 1 {
-		input := os.Args[1]
-		if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
-			resp, err := http.Get(input)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error fetching URL: %v\n", err)
-				os.Exit(1)
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				fmt.Fprintf(os.Stderr, "Error fetching URL: status %d\n", resp.StatusCode)
-				os.Exit(1)
-			}
-			reader = resp.Body
+		return os.Args[1]
+	}
+	return ""
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "demo" {
+		var err error
+		if len(os.Args) > 2 {
+			err = printSample(os.Stdout, os.Args[2])
 		} else {
-			file, err := os.Open(input)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
-				os.Exit(1)
-			}
-			defer file.Close()
-			reader = file
+			err = runDemo(os.Stdin, os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if run, ok := commands[commandName()]; ok {
+		if err := run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitStatus(err))
 		}
+		return
 	}
 
-	var jsonData interface{}
-	if err := json.NewDecoder(reader).Decode(&jsonData); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing JSON: %v\n", err)
+	fs := flag.NewFlagSet("json-shape", flag.ExitOnError)
+	format := fs.String("format", "tree", "output format: "+formatNames())
+	inputs := addInputFlags(fs)
+	lint := fs.Bool("lint", false, "report problematic key names after the output")
+	naming := fs.String("naming", "", "with --lint, the naming convention keys must follow: "+strings.Join(namingConventions, ", ")+" (default: the one most keys follow)")
+	stats := fs.Bool("stats", false, "include value statistics (ranges, lengths, item counts)")
+	budget := fs.Int("budget", 0, "maximum size in bytes of compact output; rare fields are omitted first")
+	include := fs.String("include", "", "comma-separated dot-path patterns of fields to keep, e.g. 'user.*,**.id'")
+	exclude := fs.String("exclude", "", "comma-separated dot-path patterns of fields to drop, e.g. 'metadata,**.debug'")
+	sortBy := fs.String("sort", "name", "order of the fields at each level: "+sortOrderNames())
+	openAPIPath := fs.String("openapi-path", "", "with --format=openapi, write a full spec describing the shape as the response of this path, e.g. '/users/{id}'")
+	method := fs.String("method", "get", "with --openapi-path, the HTTP method of the operation")
+	baselinePath := fs.String("baseline", "", "with --format=badge or badge-svg, a shape saved with --format=compact to report drift from")
+	assertRequired := fs.String("assert-required", "", "comma-separated paths of fields every object must hold, e.g. 'id,items[].id'; exits with status 3 otherwise")
+	assertNoUnknown := fs.Bool("assert-no-unknown-types", false, "exit with status 3 when a field was only seen as null or empty arrays")
+	describe := fs.Bool("describe", false, "draft field descriptions from the values seen, e.g. 'ISO country code, 2 letters, 14 distinct values' (markdown, jsonschema, openapi)")
+	detect := fs.String("detect", "", "with --describe, comma-separated string formats to recognize: "+detectorNames()+", or none (default: all)")
+	assumeRequiredFlag := fs.Bool("assume-required", false, "treat the fields of a single-document input, which one sample cannot show to be optional, as required, marked 'assumed required' (the default)")
+	assumeUnknownFlag := fs.Bool("assume-unknown", false, "treat the fields of a single-document input as possibly missing, marked 'presence unknown', so that schemas do not require them")
+	quiet := fs.Bool("quiet", false, "do not write the shape, only warnings and failed assertions")
+	configPath := fs.String("config", "", "file of default flags (default: the nearest "+configFileName+" in the working directory or its parents)")
+	profile := fs.String("profile", "", "profile of the config file whose flags to apply")
+	maxOutputBytes := fs.Int64("max-output-bytes", 0, "fail with status 4, writing nothing, when the shape is larger than this (0: no limit)")
+	fs.Parse(os.Args[1:])
+	if err := applyConfig(fs, *configPath, *profile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fields := analyzeJSON(jsonData)
-	printTree(fields, "", true)
+	render, ok := formats[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want one of: %s)\n", *format, formatNames())
+		os.Exit(1)
+	}
+	filter, err := parseFilter(*include, *exclude)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *naming != "" && !slices.Contains(namingConventions, *naming) {
+		fmt.Fprintf(os.Stderr, "Error: unknown naming convention %q (want one of: %s)\n", *naming, strings.Join(namingConventions, ", "))
+		os.Exit(1)
+	}
+	asserts, err := parseAssertions(*assertRequired, *assertNoUnknown)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	policy, err := parseOptionalityPolicy(*assumeRequiredFlag, *assumeUnknownFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	skipFormats, err := parseDetectors(*detect)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --detect: %v\n", err)
+		os.Exit(1)
+	}
+	if !slices.Contains(sortOrders, *sortBy) {
+		fmt.Fprintf(os.Stderr, "Error: unknown sort order %q (want one of: %s)\n", *sortBy, sortOrderNames())
+		os.Exit(1)
+	}
+	if *sortBy == "original" {
+		inputs.keyOrder = newKeyOrder()
+	}
+	if err := checkOpenAPIOperation(*openAPIPath, *method); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var baseline *shapeBaseline
+	if *baselinePath != "" {
+		if baseline, err = readBaseline(*baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --baseline: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	a, err := inputs.analyze(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitStatus(err))
+	}
+
+	fields := a.shape()
+	if a.single() {
+		assumeOptionality(fields, policy)
+	}
+	filter.apply(fields)
+	if *sortBy == "original" && inputs.keyOrder.empty() {
+		fmt.Fprintln(os.Stderr, "warning: --sort=original: key order is only kept for JSON, spreadsheet and CSV input; sorting by name")
+	}
+	sortFields(fields, *sortBy, inputs.keyOrder)
+	opts := &renderOptions{stats: *stats, budget: *budget, describe: *describe, skipFormats: skipFormats, openAPIPath: *openAPIPath, openAPIMethod: *method, openAPIList: a.list(), baseline: baseline}
+	if !*quiet {
+		var out io.Writer = os.Stdout
+		capped := &cappedWriter{limit: *maxOutputBytes}
+		if capped.limit > 0 {
+			out = capped
+		}
+		err := render(out, fields, opts)
+		if capped.exceeded {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", capped.err())
+			os.Exit(exitLimitExceeded)
+		}
+		if err == nil && capped.limit > 0 {
+			_, err = capped.buf.WriteTo(os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		for _, warning := range opts.warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", *format, warning)
+		}
+	}
+	if *lint {
+		for _, warning := range lintKeys(fields, *naming) {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
+	}
+	if failures := asserts.check(fields); len(failures) > 0 {
+		for _, failure := range failures {
+			fmt.Fprintln(os.Stderr, failure)
+		}
+		os.Exit(exitAssertionFailed)
+	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"os"
 	"strings"
@@ -46,8 +47,11 @@ func TestFinalizeOptionality(t *testing.T) {
 	if !fields["optional"].Optional {
 		t.Error("expected 'optional' to be optional")
 	}
-	if !fields["withNull"].Optional {
-		t.Error("expected 'withNull' to be optional")
+	if fields["withNull"].Optional || !fields["withNull"].Nullable {
+		t.Error("expected 'withNull' to be nullable but not optional")
+	}
+	if fields["required"].Nullable || fields["optional"].Nullable {
+		t.Error("expected fields never seen as null not to be nullable")
 	}
 }
 
@@ -55,19 +59,19 @@ func TestMergeField(t *testing.T) {
 	fields := make(map[string]*FieldInfo)
 
 	// First merge
-	mergeField(fields, "a", 1.0)
+	new(analyzer).mergeField(fields, "a", 1.0)
 	if fields["a"].Type != "number" || fields["a"].count != 1 {
 		t.Errorf("first merge failed: %+v", fields["a"])
 	}
 
 	// Second merge (same type)
-	mergeField(fields, "a", 2.0)
+	new(analyzer).mergeField(fields, "a", 2.0)
 	if fields["a"].count != 2 {
 		t.Errorf("second merge count failed: %d", fields["a"].count)
 	}
 
 	// Merge with null
-	mergeField(fields, "b", nil)
+	new(analyzer).mergeField(fields, "b", nil)
 	if !fields["b"].hasNull || fields["b"].count != 1 {
 		t.Errorf("merge null failed: %+v", fields["b"])
 	}
@@ -163,12 +167,12 @@ func TestAnalyzeJSONNullField(t *testing.T) {
 	fields := analyzeJSON(data)
 
 	if avatar, ok := fields["avatar"]; ok {
-		// If it's just null, it should be "unknown" and "optional"
+		// If it's just null, it should be "unknown" and nullable
 		if avatar.Type != "unknown" {
 			t.Errorf("expected type 'unknown' for null field, got %q", avatar.Type)
 		}
-		if !avatar.Optional {
-			t.Error("expected null field to be optional")
+		if avatar.Optional || !avatar.Nullable {
+			t.Error("expected null field to be nullable but not optional")
 		}
 	} else {
 		t.Fatal("avatar field missing")
@@ -185,8 +189,8 @@ func TestAnalyzeJSONNullUpgrade(t *testing.T) {
 	if fields["a"].Type != "string" {
 		t.Errorf("expected type 'string' after upgrade from null, got %q", fields["a"].Type)
 	}
-	if !fields["a"].Optional {
-		t.Error("expected upgraded null field to be optional")
+	if fields["a"].Optional || !fields["a"].Nullable {
+		t.Error("expected upgraded null field to be nullable but not optional")
 	}
 }
 
@@ -228,6 +232,23 @@ func TestPrintTree(t *testing.T) {
 	}
 }
 
+func TestFieldMarkers(t *testing.T) {
+	tests := []struct {
+		field *FieldInfo
+		want  string
+	}{
+		{&FieldInfo{}, ""},
+		{&FieldInfo{Optional: true}, " (optional)"},
+		{&FieldInfo{Nullable: true}, " (nullable)"},
+		{&FieldInfo{Optional: true, Nullable: true}, " (optional, nullable)"},
+	}
+	for _, tt := range tests {
+		if got := fieldMarkers(tt.field); got != tt.want {
+			t.Errorf("fieldMarkers(%+v) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
 func TestMainIntegration(t *testing.T) {
 	// Create a temporary JSON file
 	content := `{"name": "test", "value": 123}`
@@ -268,3 +289,131 @@ func TestMainIntegration(t *testing.T) {
 		t.Errorf("Integration test output mismatch:\n%s", output)
 	}
 }
+
+func TestAnalyzeJSONArrayElementOptionality(t *testing.T) {
+	// A field missing from some elements of an array within one document is
+	// optional, measured against the elements rather than the array.
+	var data interface{}
+	json.Unmarshal([]byte(`{"users": [{"id": 1, "seen": "today"}, {"id": 2}]}`), &data)
+	fields := analyzeJSON(data)
+
+	users := fields["users"].Children
+	if users["id"].Optional {
+		t.Error("id is in every element and should not be optional")
+	}
+	if !users["seen"].Optional {
+		t.Error("seen is missing from an element and should be optional")
+	}
+}
+
+func TestAnalyzeJSONObjectsCount(t *testing.T) {
+	// objects counts every object a field held, across documents and array
+	// elements, where count counts the documents that held the field.
+	var data interface{}
+	json.Unmarshal([]byte(`[
+		{"users": [{"id": 1}, {"id": 2}], "owner": {"id": 1}},
+		{"users": [{"id": 3, "seen": "today"}]},
+		{"users": [{"id": 4}, {"id": 5}]}
+	]`), &data)
+	fields := analyzeJSON(data)
+
+	tests := []struct {
+		key     string
+		count   int
+		objects int
+	}{
+		{"users", 3, 5},
+		{"owner", 1, 1},
+	}
+	for _, tt := range tests {
+		field := fields[tt.key]
+		if field.count != tt.count || field.objects != tt.objects {
+			t.Errorf("%s: count %d, objects %d; want %d, %d", tt.key, field.count, field.objects, tt.count, tt.objects)
+		}
+	}
+	if !fields["users"].Children["seen"].Optional {
+		t.Error("seen is in 1 of 5 users and should be optional")
+	}
+}
+
+func TestAnalyzeJSONOptionalityAcrossDocuments(t *testing.T) {
+	// Counting the objects a field held changes nothing for root fields,
+	// nested objects and arrays of scalars: each document holds one of them,
+	// so their fields are still measured against the documents.
+	var data interface{}
+	json.Unmarshal([]byte(`[
+		{"id": 1, "tags": ["a"], "owner": {"id": 1, "name": "a"}},
+		{"id": 2, "tags": [], "owner": {"id": 2}},
+		{"name": "x"}
+	]`), &data)
+	fields := analyzeJSON(data)
+
+	tests := []struct {
+		field    *FieldInfo
+		path     string
+		optional bool
+	}{
+		{fields["id"], "id", true},
+		{fields["name"], "name", true},
+		{fields["tags"], "tags", true},
+		{fields["owner"], "owner", true},
+		{fields["owner"].Children["id"], "owner.id", false},
+		{fields["owner"].Children["name"], "owner.name", true},
+	}
+	for _, tt := range tests {
+		if tt.field.Optional != tt.optional {
+			t.Errorf("%s: optional = %v; want %v", tt.path, tt.field.Optional, tt.optional)
+		}
+	}
+}
+
+func TestAnalyzerAdd(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "a"},
+		[]interface{}{
+			map[string]interface{}{"id": 2.0, "tags": []interface{}{"x"}},
+			"not an object",
+			map[string]interface{}{"id": "3"},
+		},
+	}
+	a := newAnalyzer()
+	for _, doc := range docs {
+		a.add(doc)
+	}
+
+	var got, want bytes.Buffer
+	if err := formats["tree"](&got, a.shape(), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	merged := analyzeJSON(mergeDocuments(docs))
+	if err := formats["tree"](&want, merged, &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("shape of documents added one at a time:\n%s\nwant the shape of the merged documents:\n%s", got.String(), want.String())
+	}
+	if a.objects != 3 || a.single() {
+		t.Errorf("objects = %d, single = %v; want 3, false", a.objects, a.single())
+	}
+	if history := a.shape()["id"].history; len(history) != 2 || history[1].doc != 4 {
+		t.Errorf("id history = %+v; want the type to change in document 4", history)
+	}
+
+	tests := []struct {
+		docs   []interface{}
+		single bool
+	}{
+		{[]interface{}{map[string]interface{}{}}, true},
+		{[]interface{}{[]interface{}{map[string]interface{}{}}}, false},
+		{[]interface{}{map[string]interface{}{}, map[string]interface{}{}}, false},
+	}
+	for _, tt := range tests {
+		a := newAnalyzer()
+		for _, doc := range tt.docs {
+			a.add(doc)
+		}
+		if a.single() != tt.single {
+			t.Errorf("single() of %v = %v; want %v", tt.docs, a.single(), tt.single)
+		}
+	}
+}
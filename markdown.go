@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxExampleLen caps the length of rendered example values.
+const maxExampleLen = 40
+
+func writeMarkdown(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	fmt.Fprintln(w, "| Field | Type | Required | Nullable | Description | Example |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- | --- | --- |")
+	writeMarkdownRows(w, fields, "", opts)
+	return nil
+}
+
+// writeMarkdownRows writes a row per field. The description is a TODO
+// placeholder, or with opts.describe, a draft made from the values seen.
+func writeMarkdownRows(w io.Writer, fields map[string]*FieldInfo, parent string, opts *renderOptions) {
+	for _, key := range sortedKeys(fields) {
+		field := fields[key]
+		path := fieldPath(parent, key)
+
+		required, nullable := "yes", "no"
+		switch {
+		case field.Assumed && field.Optional:
+			required = "unknown"
+		case field.Assumed:
+			required = "yes (assumed)"
+		case field.Optional:
+			required = "no"
+		}
+		if field.Nullable {
+			nullable = "yes"
+		}
+		example := ""
+		if field.example != nil {
+			example = "`" + formatExample(field.example) + "`"
+		}
+		description := "TODO"
+		if opts.describe {
+			if d := describeField(field, opts); d != "" {
+				description = d
+			}
+		}
+		fmt.Fprintf(w, "| `%s` | `%s` | %s | %s | %s | %s |\n",
+			markdownEscape(path), markdownEscape(displayType(field)), required, nullable, markdownEscape(description), markdownEscape(example))
+
+		if len(field.Children) > 0 {
+			writeMarkdownRows(w, field.Children, childPath(path, field), opts)
+		}
+	}
+}
+
+// formatExample renders value as compact JSON, truncated to maxExampleLen.
+func formatExample(value interface{}) string {
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	runes := []rune(string(out))
+	if len(runes) > maxExampleLen {
+		return string(runes[:maxExampleLen-1]) + "…"
+	}
+	return string(runes)
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{
+			"name": "Alice",
+			"tags": []interface{}{
+				map[string]interface{}{"id": 1.0, "label": "a|b"},
+			},
+		},
+		map[string]interface{}{"name": "Bob", "age": 30.0, "nick": nil},
+	}
+
+	var buf bytes.Buffer
+	if err := writeMarkdown(&buf, analyzeJSON(data), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLines := []string{
+		"| Field | Type | Required | Nullable | Description | Example |",
+		"| `age` | `number` | no | no | TODO | `30` |",
+		"| `name` | `string` | yes | no | TODO | `\"Alice\"` |",
+		"| `nick` | `unknown` | no | yes | TODO |  |",
+		"| `tags` | `array<object>` | no | no | TODO |  |",
+		"| `tags[].id` | `number` | yes | no | TODO | `1` |",
+		"| `tags[].label` | `string` | yes | no | TODO | `\"a\\|b\"` |",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("output missing expected line: %q\nGot:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestFormatExample(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{"hello", `"hello"`},
+		{42.0, "42"},
+		{true, "true"},
+		{[]interface{}{"a", "b"}, `["a","b"]`},
+		{strings.Repeat("é", 50), `"` + strings.Repeat("é", 38) + "…"},
+	}
+
+	for _, tt := range tests {
+		if result := formatExample(tt.input); result != tt.expected {
+			t.Errorf("formatExample(%v) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
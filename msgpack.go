@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// msgpackDecoder decodes a stream of MessagePack values. Binary data and
+// extension types are converted to base64 strings, except timestamps (type
+// -1) which become RFC 3339 strings.
+type msgpackDecoder struct {
+	r *bufio.Reader
+}
+
+func newMsgpackDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return &msgpackDecoder{r: newBufferedReader(r)}
+}
+
+func (d *msgpackDecoder) Decode() (interface{}, error) {
+	if _, err := d.r.Peek(1); err != nil {
+		return nil, err
+	}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %w", unexpectedEOF(err))
+	}
+	return v, nil
+}
+
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	buf, err := readBytes(d.r, uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(buf[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(buf)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(buf)), nil
+	default:
+		return binary.BigEndian.Uint64(buf), nil
+	}
+}
+
+func (d *msgpackDecoder) value(depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("nesting exceeds %d levels", maxDecodeDepth)
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return float64(b), nil
+	case b >= 0xe0:
+		return float64(int8(b)), nil
+	case b >= 0x80 && b <= 0x8f:
+		return d.mapValue(uint64(b&0x0f), depth)
+	case b >= 0x90 && b <= 0x9f:
+		return d.array(uint64(b&0x0f), depth)
+	case b >= 0xa0 && b <= 0xbf:
+		return d.str(uint64(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		buf, err := readBytes(d.r, n)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(buf), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(n)
+	case 0xca:
+		n, err := d.uint(4)
+		return floatValue(float64(math.Float32frombits(uint32(n)))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return floatValue(math.Float64frombits(n)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (b - 0xcc))
+		return float64(n), err
+	case 0xd0:
+		n, err := d.uint(1)
+		return float64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return float64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return float64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return float64(int64(n)), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (b - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(n, depth)
+	}
+	return nil, fmt.Errorf("invalid type byte 0x%02x", b)
+}
+
+func (d *msgpackDecoder) str(n uint64) (interface{}, error) {
+	buf, err := readBytes(d.r, n)
+	if err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
+
+func (d *msgpackDecoder) array(n uint64, depth int) (interface{}, error) {
+	arr := make([]interface{}, 0, capacity(n))
+	for i := uint64(0); i < n; i++ {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (d *msgpackDecoder) mapValue(n uint64, depth int) (interface{}, error) {
+	m := make(map[string]interface{}, capacity(n))
+	for i := uint64(0); i < n; i++ {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[mapKey(k)] = v
+	}
+	return m, nil
+}
+
+func (d *msgpackDecoder) ext(n uint64) (interface{}, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := readBytes(d.r, n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ) == -1 {
+		var t time.Time
+		switch len(buf) {
+		case 4:
+			t = time.Unix(int64(binary.BigEndian.Uint32(buf)), 0)
+		case 8:
+			v := binary.BigEndian.Uint64(buf)
+			t = time.Unix(int64(v&0x3ffffffff), int64(v>>34))
+		case 12:
+			t = time.Unix(int64(binary.BigEndian.Uint64(buf[4:])), int64(binary.BigEndian.Uint32(buf)))
+		default:
+			return nil, fmt.Errorf("invalid timestamp length %d", len(buf))
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
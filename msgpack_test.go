@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestMsgpackDecoder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"82a7636f6d70616374c3a6736368656d6100", map[string]interface{}{"compact": true, "schema": 0.0}},
+		{"d080", -128.0},
+		{"e0", -32.0},
+		{"cd0100", 256.0},
+		{"cb3ff8000000000000", 1.5},
+		{"ca7fc00000", "NaN"},
+		{"cbfff0000000000000", "-Infinity"},
+		{"c403010203", "AQID"},
+		{"d6ff00000000", "1970-01-01T00:00:00Z"},
+		{"92c0a3616263", []interface{}{nil, "abc"}},
+		{"8101a178", map[string]interface{}{"1": "x"}},
+	}
+
+	for _, tt := range tests {
+		input, _ := hex.DecodeString(tt.input)
+		result, err := newMsgpackDecoder(bytes.NewReader(input), nil).Decode()
+		if err != nil {
+			t.Errorf("Decode(%s) error: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("Decode(%s) = %#v; want %#v", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestMsgpackDecoderStream(t *testing.T) {
+	input, _ := hex.DecodeString("81a1610181a16102")
+	dec := newMsgpackDecoder(bytes.NewReader(input), nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("document %d: %v", i, err)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected io.EOF after last document, got %v", err)
+	}
+}
+
+func TestMsgpackDecoderTruncated(t *testing.T) {
+	input, _ := hex.DecodeString("82a7636f6d70")
+	if _, err := newMsgpackDecoder(bytes.NewReader(input), nil).Decode(); err == nil || err == io.EOF {
+		t.Errorf("expected error for truncated input, got %v", err)
+	}
+}
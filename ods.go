@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxRepeatedRows bounds the expansion of table:number-rows-repeated, which
+// generators also use to pad a sheet with blank rows to its full height.
+const maxRepeatedRows = 1 << 16
+
+type odsCell struct {
+	ValueType  string `xml:"value-type,attr"`
+	Value      string `xml:"value,attr"`
+	DateValue  string `xml:"date-value,attr"`
+	TimeValue  string `xml:"time-value,attr"`
+	BoolValue  string `xml:"boolean-value,attr"`
+	Repeated   int    `xml:"number-columns-repeated,attr"`
+	Paragraphs []struct {
+		Text  string `xml:",chardata"`
+		Spans []struct {
+			Text string `xml:",chardata"`
+		} `xml:"span"`
+	} `xml:"p"`
+}
+
+func (c odsCell) value() interface{} {
+	switch c.ValueType {
+	case "float", "percentage", "currency":
+		if f, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			return f
+		}
+		return c.Value
+	case "date":
+		return c.DateValue
+	case "time":
+		return c.TimeValue
+	case "boolean":
+		return c.BoolValue == "true"
+	}
+
+	if len(c.Paragraphs) == 0 {
+		return nil
+	}
+	lines := make([]string, len(c.Paragraphs))
+	for i, p := range c.Paragraphs {
+		lines[i] = p.Text
+		for _, span := range p.Spans {
+			lines[i] += span.Text
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readODS returns the cell values of one table of an OpenDocument
+// spreadsheet. Dates and times keep their ISO 8601 form.
+func readODS(zr *zip.Reader, sheet string, size *sheetSize) ([][]interface{}, error) {
+	f, err := openZipFile(zr, "content.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var names []string
+	var rows [][]interface{}
+	found := false
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("content.xml: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "table" {
+			continue
+		}
+
+		name := attr(start, "name")
+		names = append(names, name)
+		if found || !odsSheetMatches(name, len(names), sheet) {
+			if err := dec.Skip(); err != nil {
+				return nil, fmt.Errorf("content.xml: %v", err)
+			}
+			continue
+		}
+		found = true
+		if rows, err = readODSTable(dec, size); err != nil {
+			return nil, fmt.Errorf("content.xml: %w", err)
+		}
+	}
+
+	if !found {
+		if _, err := selectSheet(names, sheet); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func odsSheetMatches(name string, position int, sheet string) bool {
+	if sheet == "" {
+		return position == 1
+	}
+	return name == sheet || sheet == strconv.Itoa(position)
+}
+
+// readODSTable reads the rows of the table whose start element was just
+// consumed, up to its end element, counting them in size.
+func readODSTable(dec *xml.Decoder, size *sheetSize) ([][]interface{}, error) {
+	var rows [][]interface{}
+	var row []interface{}
+	repeat, col := 1, 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "table-row":
+				row, col = nil, 0
+				repeat = 1
+				if n, err := strconv.Atoi(attr(t, "number-rows-repeated")); err == nil && n > 1 {
+					repeat = n
+				}
+			case "table-cell", "covered-table-cell":
+				var cell odsCell
+				if err := dec.DecodeElement(&cell, &t); err != nil {
+					return nil, err
+				}
+				// Empty cells repeated to pad a row to its full width
+				// only move the column on.
+				n := min(max(cell.Repeated, 1), maxColumns)
+				if v := cell.value(); v != nil {
+					for i := 0; i < n; i++ {
+						if row, err = setCell(row, col+i, v); err != nil {
+							return nil, err
+						}
+					}
+				}
+				col = min(col+n, maxColumns)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "table-row":
+				if isEmptyRow(row) {
+					continue
+				}
+				n := min(repeat, maxRepeatedRows)
+				if err := size.add(row, n); err != nil {
+					return nil, err
+				}
+				for i := 0; i < n; i++ {
+					rows = append(rows, row)
+				}
+			case "table":
+				return rows, nil
+			}
+		}
+	}
+}
+
+// attr returns the value of the attribute with the given local name.
+func attr(start xml.StartElement, local string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
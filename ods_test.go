@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const odsContent = `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>
+<table:table table:name="Empty"><table:table-row table:number-rows-repeated="1048576"><table:table-cell table:number-columns-repeated="1024"/></table:table-row></table:table>
+<table:table table:name="Readings">
+<table:table-row><table:table-cell office:value-type="string"><text:p>sensor</text:p></table:table-cell><table:table-cell office:value-type="string"><text:p>taken</text:p></table:table-cell><table:table-cell office:value-type="string"><text:p>ok</text:p></table:table-cell><table:table-cell office:value-type="string"><text:p>value</text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="2"><table:table-cell office:value-type="string"><text:p>t<text:span>1</text:span></text:p></table:table-cell><table:table-cell office:value-type="date" office:date-value="2024-03-01T08:00:00"/><table:table-cell office:value-type="boolean" office:boolean-value="true"/><table:table-cell office:value-type="percentage" office:value="0.25"/></table:table-row>
+<table:table-row><table:table-cell office:value-type="string"><text:p>t2</text:p></table:table-cell><table:covered-table-cell/><table:table-cell table:number-columns-repeated="1"/><table:table-cell office:value-type="float" office:value="3"/><table:table-cell table:number-columns-repeated="1020"/></table:table-row>
+<table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+</office:spreadsheet></office:body></office:document-content>`
+
+func TestODSDecoder(t *testing.T) {
+	data := zipArchive(t, map[string]string{"content.xml": odsContent})
+	result, err := newODSDecoder(bytes.NewReader(data), &decodeOptions{sheet: "2"}).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	reading := map[string]interface{}{"sensor": "t1", "taken": "2024-03-01T08:00:00", "ok": true, "value": 0.25}
+	expected := []interface{}{
+		reading,
+		reading,
+		map[string]interface{}{"sensor": "t2", "value": 3.0},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Decode() = %#v; want %#v", result, expected)
+	}
+}
+
+func TestODSDecoderSheetSelection(t *testing.T) {
+	data := zipArchive(t, map[string]string{"content.xml": odsContent})
+	result, err := newODSDecoder(bytes.NewReader(data), nil).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if rows := result.([]interface{}); len(rows) != 0 {
+		t.Errorf("expected the blank first sheet to have no rows, got %v", rows)
+	}
+
+	if _, err := newODSDecoder(bytes.NewReader(data), &decodeOptions{sheet: "Missing"}).Decode(); err == nil {
+		t.Error("expected an error for an unknown sheet")
+	}
+}
+
+func TestODSDecoderColumnLimit(t *testing.T) {
+	content := `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet><table:table table:name="Wide">
+<table:table-row><table:table-cell table:number-columns-repeated="1000000000" office:value-type="float" office:value="1"/><table:table-cell office:value-type="float" office:value="2"/></table:table-row>
+</table:table></office:spreadsheet></office:body></office:document-content>`
+	data := zipArchive(t, map[string]string{"content.xml": content})
+	if _, err := newODSDecoder(bytes.NewReader(data), nil).Decode(); err == nil {
+		t.Error("expected an error for a cell beyond maxColumns")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const openAPIVersion = "3.1.0"
+
+// openAPIDocument is the subset of an OpenAPI 3.1 description that shapes
+// map onto. Its schemas are JSON Schema 2020-12, as in the jsonschema
+// format. Paths, operations and components are maps, which encoding/json
+// writes in key order. A document with only components is a fragment to
+// merge into an existing description.
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi,omitempty"`
+	Info       *openAPIInfo                            `json:"info,omitempty"`
+	Servers    []openAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths,omitzero"`
+	Components *openAPIComponents                      `json:"components,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*jsonSchema `json:"schemas"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Content map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+// writeOpenAPI writes an OpenAPI description of the routes seen, served by
+// target: the path, query and header parameters, request body and
+// per-status response bodies of each operation.
+func (c *captureRecorder) writeOpenAPI(w io.Writer, target *url.URL, opts *renderOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    &openAPIInfo{Title: target.Host, Version: "0.0.0"},
+		Servers: []openAPIServer{{URL: target.String()}},
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	for _, rt := range c.sortedRoutes() {
+		capture := c.routes[rt]
+		op := &openAPIOperation{Parameters: pathParameters(rt.path)}
+		op.Parameters = append(op.Parameters, requestParameters("query", capture.queries)...)
+		op.Parameters = append(op.Parameters, requestParameters("header", capture.headers)...)
+		if len(capture.requests) > 0 {
+			op.RequestBody = &openAPIRequestBody{Content: mediaContent(capture.requests, opts)}
+		}
+		for _, status := range capture.statuses() {
+			if op.Responses == nil {
+				op.Responses = make(map[string]*openAPIResponse)
+			}
+			op.Responses[strconv.Itoa(status)] = &openAPIResponse{
+				Description: http.StatusText(status),
+				Content:     mediaContent(capture.responsesOf(status), opts),
+			}
+		}
+
+		if doc.Paths[rt.path] == nil {
+			doc.Paths[rt.path] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+	return writeOpenAPIDocument(w, doc)
+}
+
+// openAPIMethods are the operations a path item can hold.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// checkOpenAPIOperation validates the --openapi-path and --method flags.
+func checkOpenAPIOperation(path, method string) error {
+	if path == "" {
+		return nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("--openapi-path must start with /, got %q", path)
+	}
+	if !slices.Contains(openAPIMethods, strings.ToLower(method)) {
+		return fmt.Errorf("unknown method %q (want one of: %s)", method, strings.Join(openAPIMethods, ", "))
+	}
+	return nil
+}
+
+// openAPIRootName names the component schema of the shape.
+const openAPIRootName = "Root"
+
+// writeOpenAPI writes the shape as the schema of an OpenAPI component,
+// describing each object of the input. By default the output is a fragment
+// holding only components.schemas; with opts.openAPIPath it is a minimal
+// description in which the shape is the 200 response of that path and
+// opts.openAPIMethod: an array of Root objects with opts.openAPIList, as
+// bodySchema describes list bodies, and a Root object otherwise.
+func writeOpenAPI(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	doc := &openAPIDocument{
+		Components: &openAPIComponents{Schemas: map[string]*jsonSchema{
+			openAPIRootName: objectSchema(fields, opts),
+		}},
+	}
+	if opts.openAPIPath != "" {
+		method := strings.ToLower(opts.openAPIMethod)
+		if method == "" {
+			method = "get"
+		}
+		body := &jsonSchema{Ref: "#/components/schemas/" + openAPIRootName}
+		if opts.openAPIList {
+			body = &jsonSchema{Type: "array", Items: body}
+		}
+		doc.OpenAPI = openAPIVersion
+		doc.Info = &openAPIInfo{Title: opts.openAPIPath, Version: "0.0.0"}
+		doc.Paths = map[string]map[string]*openAPIOperation{
+			opts.openAPIPath: {method: {
+				Parameters: pathParameters(opts.openAPIPath),
+				Responses: map[string]*openAPIResponse{"200": {
+					Description: http.StatusText(http.StatusOK),
+					Content:     map[string]openAPIMediaType{"application/json": {Schema: body}},
+				}},
+			}},
+		}
+	}
+	return writeOpenAPIDocument(w, doc)
+}
+
+func writeOpenAPIDocument(w io.Writer, doc *openAPIDocument) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pathParameters declares the parameters of a route template such as
+// "/users/{id}/orders/{id2}".
+func pathParameters(path string) []openAPIParameter {
+	var params []openAPIParameter
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, openAPIParameter{
+				Name:     segment[1 : len(segment)-1],
+				In:       "path",
+				Required: true,
+				Schema:   &jsonSchema{Type: "string"},
+			})
+		}
+	}
+	return params
+}
+
+// mediaContent returns the content of a request or response: the schema of
+// the bodies of each media type.
+func mediaContent(bodies map[string][]interface{}, opts *renderOptions) map[string]openAPIMediaType {
+	content := make(map[string]openAPIMediaType, len(bodies))
+	for mediaType, docs := range bodies {
+		content[mediaType] = openAPIMediaType{Schema: bodySchema(docs, opts)}
+	}
+	return content
+}
+
+// bodySchema returns the schema of the bodies docs: an array of objects when
+// every body is an array, otherwise an object.
+func bodySchema(docs []interface{}, opts *renderOptions) *jsonSchema {
+	schema := objectSchema(analyzeJSON(mergeDocuments(docs)), opts)
+	for _, doc := range docs {
+		if _, ok := doc.([]interface{}); !ok {
+			return schema
+		}
+	}
+	return &jsonSchema{Type: "array", Items: schema}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestCaptureRecorderOpenAPI(t *testing.T) {
+	rec := newCaptureRecorder()
+	header := http.Header{"Content-Type": {"application/json"}}
+	rec.record(rec.routeFor("POST", "/users"), header, []byte(`{"name": "Ada"}`), 0)
+	rec.record(rec.routeFor("POST", "/users"), header, []byte(`{"id": 7, "name": "Ada"}`), http.StatusCreated)
+	problem := http.Header{"Content-Type": {"application/problem+json"}}
+	rec.record(rec.routeFor("POST", "/users"), problem, []byte(`{"title": "taken"}`), http.StatusConflict)
+	rec.record(rec.routeFor("POST", "/users"), header, []byte(`{"error": "taken"}`), http.StatusConflict)
+	rec.record(rec.routeFor("GET", "/users"), header, []byte(`[{"id": 7}, {"id": 8, "nick": null}]`), http.StatusOK)
+	rec.record(rec.routeFor("GET", "/users/7"), header, []byte(`{"id": 7}`), http.StatusOK)
+
+	target, _ := url.Parse("https://api.example.com/v1")
+	var buf bytes.Buffer
+	if err := rec.writeOpenAPI(&buf, target, &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	object := func(props map[string]interface{}, required ...interface{}) map[string]interface{} {
+		schema := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	content := func(schema map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+	}
+	number := map[string]interface{}{"type": "number"}
+	str := map[string]interface{}{"type": "string"}
+
+	expected := map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info":    map[string]interface{}{"title": "api.example.com", "version": "0.0.0"},
+		"servers": []interface{}{map[string]interface{}{"url": "https://api.example.com/v1"}},
+		"paths": map[string]interface{}{
+			"/users": map[string]interface{}{
+				"get": map[string]interface{}{
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "OK",
+							"content": content(map[string]interface{}{
+								"type":  "array",
+								"items": object(map[string]interface{}{"id": number, "nick": map[string]interface{}{"type": "null"}}, "id"),
+							}),
+						},
+					},
+				},
+				"post": map[string]interface{}{
+					"requestBody": map[string]interface{}{
+						"content": content(object(map[string]interface{}{"name": str}, "name")),
+					},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{
+							"description": "Created",
+							"content":     content(object(map[string]interface{}{"id": number, "name": str}, "id", "name")),
+						},
+						"409": map[string]interface{}{
+							"description": "Conflict",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": object(map[string]interface{}{"error": str}, "error"),
+								},
+								"application/problem+json": map[string]interface{}{
+									"schema": object(map[string]interface{}{"title": str}, "title"),
+								},
+							},
+						},
+					},
+				},
+			},
+			"/users/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"parameters": []interface{}{
+						map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": str},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "OK",
+							"content":     content(object(map[string]interface{}{"id": number}, "id")),
+						},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("unexpected document:\n%s", buf.String())
+	}
+}
+
+func TestPathParameters(t *testing.T) {
+	params := pathParameters("/users/{id}/orders/{id2}")
+	if len(params) != 2 || params[0].Name != "id" || params[1].Name != "id2" || !params[1].Required {
+		t.Errorf("pathParameters() = %+v", params)
+	}
+	if params := pathParameters("/health"); params != nil {
+		t.Errorf("expected no parameters, got %+v", params)
+	}
+}
+
+func TestWriteOpenAPI(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "Ada"},
+		map[string]interface{}{"id": 2.0},
+	}
+	root := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":   map[string]interface{}{"type": "number"},
+			"name": map[string]interface{}{"type": "string"},
+		},
+		"required": []interface{}{"id"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeOpenAPI(&buf, analyzeJSON(data), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	fragment := map[string]interface{}{
+		"components": map[string]interface{}{"schemas": map[string]interface{}{"Root": root}},
+	}
+	if !reflect.DeepEqual(doc, fragment) {
+		t.Errorf("unexpected fragment:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	opts := &renderOptions{openAPIPath: "/users/{id}", openAPIMethod: "GET"}
+	if err := writeOpenAPI(&buf, analyzeJSON(data), opts); err != nil {
+		t.Fatal(err)
+	}
+	doc = nil
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	spec := map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info":    map[string]interface{}{"title": "/users/{id}", "version": "0.0.0"},
+		"paths": map[string]interface{}{
+			"/users/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"parameters": []interface{}{
+						map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "OK",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/Root"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"components": map[string]interface{}{"schemas": map[string]interface{}{"Root": root}},
+	}
+	if !reflect.DeepEqual(doc, spec) {
+		t.Errorf("unexpected spec:\n%s", buf.String())
+	}
+}
+
+func TestWriteOpenAPIList(t *testing.T) {
+	tests := []struct {
+		docs   []interface{}
+		schema string
+	}{
+		{[]interface{}{[]interface{}{map[string]interface{}{"id": 1.0}}}, `{"type":"array","items":{"$ref":"#/components/schemas/Root"}}`},
+		{[]interface{}{map[string]interface{}{"id": 1.0}}, `{"$ref":"#/components/schemas/Root"}`},
+		{[]interface{}{[]interface{}{map[string]interface{}{"id": 1.0}}, map[string]interface{}{"id": 2.0}}, `{"$ref":"#/components/schemas/Root"}`},
+	}
+	for _, tt := range tests {
+		a := newAnalyzer()
+		for _, doc := range tt.docs {
+			a.add(doc)
+		}
+		var buf bytes.Buffer
+		opts := &renderOptions{openAPIPath: "/users", openAPIList: a.list()}
+		if err := writeOpenAPI(&buf, a.shape(), opts); err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Paths map[string]map[string]struct {
+				Responses map[string]struct {
+					Content map[string]struct {
+						Schema json.RawMessage `json:"schema"`
+					} `json:"content"`
+				} `json:"responses"`
+			} `json:"paths"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+		}
+		var schema bytes.Buffer
+		json.Compact(&schema, doc.Paths["/users"]["get"].Responses["200"].Content["application/json"].Schema)
+		if schema.String() != tt.schema {
+			t.Errorf("response schema of %v = %s; want %s", tt.docs, schema.String(), tt.schema)
+		}
+	}
+}
+
+func TestCheckOpenAPIOperation(t *testing.T) {
+	tests := []struct {
+		path, method string
+		wantErr      bool
+	}{
+		{"", "anything", false},
+		{"/users", "POST", false},
+		{"users", "get", true},
+		{"/users", "fetch", true},
+	}
+	for _, tt := range tests {
+		if err := checkOpenAPIOperation(tt.path, tt.method); (err != nil) != tt.wantErr {
+			t.Errorf("checkOpenAPIOperation(%q, %q) = %v, want error %v", tt.path, tt.method, err, tt.wantErr)
+		}
+	}
+}
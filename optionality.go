@@ -0,0 +1,54 @@
+package main
+
+import "fmt"
+
+// optionalityPolicy is how to treat fields that only one object could hold,
+// whose optionality a single sample cannot establish.
+type optionalityPolicy int
+
+const (
+	// assumeRequired keeps such fields required, as they were present.
+	assumeRequired optionalityPolicy = iota
+	// assumeUnknown treats them as possibly missing, so that schemas do not
+	// require them.
+	assumeUnknown
+)
+
+// parseOptionalityPolicy returns the policy selected by the
+// --assume-required and --assume-unknown flags.
+func parseOptionalityPolicy(required, unknown bool) (optionalityPolicy, error) {
+	if required && unknown {
+		return 0, fmt.Errorf("--assume-required and --assume-unknown cannot be used together")
+	}
+	if unknown {
+		return assumeUnknown, nil
+	}
+	return assumeRequired, nil
+}
+
+// assumedComment returns a trailing comment for code output saying how the
+// optionality of field was assumed, or "" when it was observed.
+func assumedComment(field *FieldInfo) string {
+	switch {
+	case field.Assumed && field.Optional:
+		return " // presence unknown: seen in a single object"
+	case field.Assumed:
+		return " // assumed required: seen in a single object"
+	}
+	return ""
+}
+
+// assumeOptionality sets the optionality of the fields of a single-document
+// input by policy, and marks them Assumed so that outputs can show how
+// little the data said about them. It covers the fields of the document and
+// of its nested objects, but not those of arrays of several objects, whose
+// presence was seen more than once.
+func assumeOptionality(fields map[string]*FieldInfo, policy optionalityPolicy) {
+	for _, field := range fields {
+		field.Assumed = true
+		field.Optional = policy == assumeUnknown
+		if field.objects == 1 {
+			assumeOptionality(field.Children, policy)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssumeOptionality(t *testing.T) {
+	doc := map[string]interface{}{
+		"id":   1.0,
+		"user": map[string]interface{}{"name": "a"},
+		"tags": []interface{}{map[string]interface{}{"x": 1.0}, map[string]interface{}{"y": 2.0}},
+	}
+
+	tests := []struct {
+		name   string
+		policy optionalityPolicy
+		want   string
+	}{
+		{"assume required", assumeRequired, `root
+├── id: number (assumed required)
+├── tags (assumed required)
+│   ├── x: number (optional)
+│   └── y: number (optional)
+└── user (assumed required)
+    └── name: string (assumed required)
+`},
+		{"assume unknown", assumeUnknown, `root
+├── id: number (presence unknown)
+├── tags (presence unknown)
+│   ├── x: number (optional)
+│   └── y: number (optional)
+└── user (presence unknown)
+    └── name: string (presence unknown)
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := analyzeJSON(doc)
+			assumeOptionality(fields, tt.policy)
+			var out strings.Builder
+			if err := formats["tree"](&out, fields, &renderOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("tree:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOptionalityPolicy(t *testing.T) {
+	tests := []struct {
+		required, unknown bool
+		want              optionalityPolicy
+		wantErr           bool
+	}{
+		{false, false, assumeRequired, false},
+		{true, false, assumeRequired, false},
+		{false, true, assumeUnknown, false},
+		{true, true, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseOptionalityPolicy(tt.required, tt.unknown)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseOptionalityPolicy(%v, %v) = %v, %v", tt.required, tt.unknown, got, err)
+		}
+	}
+}
+
+func TestAssumedMarkers(t *testing.T) {
+	doc := map[string]interface{}{"id": 1.0}
+
+	tests := []struct {
+		format string
+		policy optionalityPolicy
+		want   string
+	}{
+		{"compact", assumeRequired, "id:number~\n"},
+		{"compact", assumeUnknown, "id:number?~\n"},
+		{"zod", assumeRequired, "  id: z.number(), // assumed required: seen in a single object\n"},
+		{"io-ts", assumeUnknown, "  id: t.number, // presence unknown: seen in a single object\n"},
+	}
+	for _, tt := range tests {
+		fields := analyzeJSON(doc)
+		assumeOptionality(fields, tt.policy)
+		var out strings.Builder
+		if err := formats[tt.format](&out, fields, &renderOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("%s, policy %v: output lacks %q:\n%s", tt.format, tt.policy, tt.want, out.String())
+		}
+	}
+}
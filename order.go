@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// sortOrders lists the --sort values, the first being the default.
+var sortOrders = []string{"name", "presence", "type", "original"}
+
+func sortOrderNames() string {
+	return strings.Join(sortOrders, ", ")
+}
+
+// keyOrder records the position at which each key was first seen among its
+// siblings, for --sort=original: decoding objects into maps loses the order
+// of their keys. Positions are kept per object path, the field names leading
+// to the object as in the shape, so that the keys of array elements share
+// the path of the array.
+type keyOrder struct {
+	positions map[string]map[string]int
+}
+
+func newKeyOrder() *keyOrder {
+	return &keyOrder{positions: make(map[string]map[string]int)}
+}
+
+// orderPath returns the path of the objects held by key within the objects
+// at parent. The root path is "".
+func orderPath(parent, key string) string {
+	return parent + "\x00" + key
+}
+
+// add records keys, in order, as keys of the objects at path. Keys already
+// seen keep their position. add does nothing on a nil keyOrder, so decoders
+// can call it unconditionally.
+func (o *keyOrder) add(path string, keys ...string) {
+	if o == nil {
+		return
+	}
+	positions, ok := o.positions[path]
+	if !ok {
+		positions = make(map[string]int)
+		o.positions[path] = positions
+	}
+	for _, key := range keys {
+		if _, seen := positions[key]; !seen {
+			positions[key] = len(positions)
+		}
+	}
+}
+
+// scan records the keys of the JSON document data in the order they appear.
+func (o *keyOrder) scan(data []byte) error {
+	if o == nil {
+		return nil
+	}
+	return o.scanValue(json.NewDecoder(bytes.NewReader(data)), "")
+}
+
+func (o *keyOrder) scanValue(dec *json.Decoder, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, ok := tok.(string)
+			if !ok {
+				return fmt.Errorf("unexpected object key %v", tok)
+			}
+			o.add(path, key)
+			if err := o.scanValue(dec, orderPath(path, key)); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for dec.More() {
+			if err := o.scanValue(dec, path); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
+
+// remap moves every recorded key to the path move gives it, dropping those
+// move drops. Keys that land among the same siblings keep their relative
+// order.
+func (o *keyOrder) remap(move keyMove) {
+	if o == nil {
+		return
+	}
+	paths := make([]string, 0, len(o.positions))
+	for path := range o.positions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	remapped := newKeyOrder()
+	for _, path := range paths {
+		parent := strings.Split(path, "\x00")[1:]
+		positions := o.positions[path]
+		keys := make([]string, 0, len(positions))
+		for key := range positions {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return positions[keys[i]] < positions[keys[j]] })
+
+		for _, key := range keys {
+			moved, ok := move(append(parent[:len(parent):len(parent)], key))
+			if !ok {
+				continue
+			}
+			to := ""
+			for _, k := range moved[:len(moved)-1] {
+				to = orderPath(to, k)
+			}
+			remapped.add(to, moved[len(moved)-1])
+		}
+	}
+	o.positions = remapped.positions
+}
+
+func (o *keyOrder) empty() bool {
+	return o == nil || len(o.positions) == 0
+}
+
+// sortFields ranks the fields at every level of the shape so that sortedKeys
+// returns them in the order named by by:
+//
+//   - name: alphabetically, the default
+//   - presence: most frequently present first
+//   - type: grouped by type label
+//   - original: in the order first seen in the input, as recorded by order;
+//     fields with no recorded position follow the others
+//
+// Ties are broken by name.
+func sortFields(fields map[string]*FieldInfo, by string, order *keyOrder) {
+	rankFields(fields, "", by, order)
+}
+
+func rankFields(fields map[string]*FieldInfo, path, by string, order *keyOrder) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	var less func(a, b string) bool
+	switch by {
+	case "presence":
+		less = func(a, b string) bool { return fields[a].count > fields[b].count }
+	case "type":
+		less = func(a, b string) bool { return displayType(fields[a]) < displayType(fields[b]) }
+	case "original":
+		var positions map[string]int
+		if order != nil {
+			positions = order.positions[path]
+		}
+		position := func(key string) int {
+			if p, ok := positions[key]; ok {
+				return p
+			}
+			return math.MaxInt
+		}
+		less = func(a, b string) bool { return position(a) < position(b) }
+	default:
+		less = func(a, b string) bool { return false }
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a < b
+	})
+
+	for i, key := range keys {
+		field := fields[key]
+		field.rank = i
+		rankFields(field.Children, orderPath(path, key), by, order)
+	}
+}
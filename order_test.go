@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyOrderScan(t *testing.T) {
+	order := newKeyOrder()
+	input := `{"z": 1, "a": {"y": 1, "b": 2}, "list": [{"k2": 1}, {"k1": 1, "k2": 2}]}
+{"m": true, "z": 2}`
+	if _, err := decodeAll(newJSONDecoder(strings.NewReader(input), &decodeOptions{keyOrder: order})); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]map[string]int{
+		"":                    {"z": 0, "a": 1, "list": 2, "m": 3},
+		orderPath("", "a"):    {"y": 0, "b": 1},
+		orderPath("", "list"): {"k2": 0, "k1": 1},
+	}
+	if !reflect.DeepEqual(order.positions, expected) {
+		t.Errorf("positions = %v, want %v", order.positions, expected)
+	}
+}
+
+func TestSortFields(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{"name": "a", "age": 1.0, "tags": []interface{}{"x"}, "zip": "1"},
+		map[string]interface{}{"name": "b", "age": 2.0, "zip": "2"},
+		map[string]interface{}{"name": "c", "zip": "3"},
+	}
+	order := newKeyOrder()
+	order.add("", "zip", "name", "age")
+
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{"name", []string{"age", "name", "tags", "zip"}},
+		{"presence", []string{"name", "zip", "age", "tags"}},
+		{"type", []string{"tags", "age", "name", "zip"}},
+		{"original", []string{"zip", "name", "age", "tags"}},
+	}
+	for _, tt := range tests {
+		fields := analyzeJSON(data)
+		sortFields(fields, tt.by, order)
+		if got := sortedKeys(fields); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("--sort=%s: keys = %v, want %v", tt.by, got, tt.want)
+		}
+	}
+}
+
+func TestSortFieldsNested(t *testing.T) {
+	order := newKeyOrder()
+	data, err := decodeAll(newJSONDecoder(strings.NewReader(`{"user": {"id": 1, "email": "a", "bio": null}}`), &decodeOptions{keyOrder: order}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := analyzeJSON(data[0])
+	sortFields(fields, "original", order)
+
+	want := []string{"id", "email", "bio"}
+	if got := sortedKeys(fields["user"].Children); !reflect.DeepEqual(got, want) {
+		t.Errorf("nested keys = %v, want %v", got, want)
+	}
+}
+
+func TestSortFieldsAfterTransform(t *testing.T) {
+	input := `{"data": {"zeta": 1, "mail": "a", "alpha": {"y": 1, "x": 2}}, "meta": {"page": 1}}`
+	tests := []struct {
+		transform string
+		want      []string
+		nested    []string
+	}{
+		{"unwrap data", []string{"zeta", "mail", "alpha"}, []string{"y", "x"}},
+		{"unwrap data; rename mail email", []string{"zeta", "email", "alpha"}, []string{"y", "x"}},
+		{"rename data.alpha beta; unwrap data", []string{"zeta", "mail", "beta"}, []string{"y", "x"}},
+	}
+	for _, tt := range tests {
+		transform, err := parseTransform(tt.transform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		order := newKeyOrder()
+		sources := []source{{name: "in.json", open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(input)), nil
+		}}}
+		docs, err := readSources(sources, "", &decodeOptions{keyOrder: order, transform: transform}, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := analyzeJSON(mergeDocuments(docs))
+		sortFields(fields, "original", order)
+
+		if got := sortedKeys(fields); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: keys = %v, want %v", tt.transform, got, tt.want)
+		}
+		object := tt.want[2]
+		if got := sortedKeys(fields[object].Children); !reflect.DeepEqual(got, tt.nested) {
+			t.Errorf("%s: keys of %s = %v, want %v", tt.transform, object, got, tt.nested)
+		}
+	}
+}
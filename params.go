@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ignoredHeaders are request headers left out of the inferred parameters:
+// those set by clients, browsers and proxies for every request rather than
+// by the API's contract. Accept, Content-Type and Authorization are also
+// ones OpenAPI forbids describing as parameters.
+var ignoredHeaders = map[string]bool{
+	"Accept":            true,
+	"Accept-Encoding":   true,
+	"Accept-Language":   true,
+	"Authorization":     true,
+	"Cache-Control":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Cookie":            true,
+	"Dnt":               true,
+	"Forwarded":         true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Origin":            true,
+	"Pragma":            true,
+	"Referer":           true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"User-Agent":        true,
+	"Via":               true,
+	"X-Real-Ip":         true,
+}
+
+// isNotableHeader reports whether the request header name, in canonical
+// form, is worth describing as a parameter.
+func isNotableHeader(name string) bool {
+	if ignoredHeaders[name] {
+		return false
+	}
+	for _, prefix := range []string{"Proxy-", "Sec-", "X-Forwarded-"} {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// paramDocument turns query parameters or headers into a document for
+// analyzeJSON, inferring the type of each value. Repeated names become
+// arrays.
+func paramDocument(values map[string][]string, keep func(name string) bool) map[string]interface{} {
+	doc := make(map[string]interface{})
+	for name, vs := range values {
+		if keep != nil && !keep(name) || len(vs) == 0 {
+			continue
+		}
+		if len(vs) == 1 {
+			doc[name] = paramValue(vs[0])
+			continue
+		}
+		items := make([]interface{}, len(vs))
+		for i, v := range vs {
+			items[i] = paramValue(v)
+		}
+		doc[name] = items
+	}
+	return doc
+}
+
+// paramValue returns s as a boolean or number when it reads as one.
+func paramValue(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	return s
+}
+
+// requestParameters describes the query parameters or headers (in, "query"
+// or "header") recorded for requests, one document per request. Those
+// present in every request are required.
+func requestParameters(in string, requests []interface{}) []openAPIParameter {
+	fields := analyzeJSON(requests)
+	var params []openAPIParameter
+	for _, name := range sortedKeys(fields) {
+		field := fields[name]
+		params = append(params, openAPIParameter{
+			Name:     name,
+			In:       in,
+			Required: field.count == len(requests),
+			Schema:   typeSchema(field.Type),
+		})
+	}
+	return params
+}
+
+// recordParams adds the query parameters and notable headers of a request
+// on rt to its samples.
+func (c *captureRecorder) recordParams(rt route, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	capture := c.capture(rt)
+	if len(capture.queries) < maxRouteSamples {
+		capture.queries = append(capture.queries, paramDocument(r.URL.Query(), nil))
+		capture.headers = append(capture.headers, paramDocument(r.Header, isNotableHeader))
+	}
+}
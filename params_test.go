@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParamDocument(t *testing.T) {
+	values := map[string][]string{
+		"page":   {"2"},
+		"active": {"true"},
+		"q":      {"shoes"},
+		"tag":    {"a", "b"},
+		"empty":  {},
+	}
+	expected := map[string]interface{}{
+		"page":   2.0,
+		"active": true,
+		"q":      "shoes",
+		"tag":    []interface{}{"a", "b"},
+	}
+	if doc := paramDocument(values, nil); !reflect.DeepEqual(doc, expected) {
+		t.Errorf("paramDocument() = %v, want %v", doc, expected)
+	}
+}
+
+func TestIsNotableHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"X-Request-Id", true},
+		{"Idempotency-Key", true},
+		{"Authorization", false},
+		{"User-Agent", false},
+		{"Sec-Fetch-Mode", false},
+		{"X-Forwarded-For", false},
+	}
+	for _, tt := range tests {
+		if got := isNotableHeader(tt.name); got != tt.want {
+			t.Errorf("isNotableHeader(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecordParams(t *testing.T) {
+	rec := newCaptureRecorder()
+	rt := rec.routeFor("GET", "/search")
+	for _, target := range []string{"/search?q=shoes&page=1", "/search?q=hats"} {
+		r := httptest.NewRequest("GET", target, nil)
+		r.Header.Set("X-Tenant", "acme")
+		r.Header.Set("User-Agent", "curl/8.0")
+		rec.recordParams(rt, r)
+	}
+
+	capture := rec.routes[rt]
+	query := requestParameters("query", capture.queries)
+	expected := []openAPIParameter{
+		{Name: "page", In: "query", Schema: &jsonSchema{Type: "number"}},
+		{Name: "q", In: "query", Required: true, Schema: &jsonSchema{Type: "string"}},
+	}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("query parameters = %+v, want %+v", query, expected)
+	}
+
+	headers := requestParameters("header", capture.headers)
+	expected = []openAPIParameter{
+		{Name: "X-Tenant", In: "header", Required: true, Schema: &jsonSchema{Type: "string"}},
+	}
+	if !reflect.DeepEqual(headers, expected) {
+		t.Errorf("header parameters = %+v, want %+v", headers, expected)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+func writeParquet(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	fmt.Fprintln(w, "message root {")
+	writeParquetGroup(w, fields, "", "  ", opts)
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+func writeParquetGroup(w io.Writer, fields map[string]*FieldInfo, parent, indent string, opts *renderOptions) {
+	keys := sortedKeys(fields)
+	names := uniqueNames(keys, parquetName)
+	for _, key := range keys {
+		field := fields[key]
+		path := fieldPath(parent, key)
+		name := names[key]
+		if name != key {
+			opts.warn(path, fmt.Sprintf("not a valid Parquet name; renamed to %q", name))
+		}
+		repetition := "required"
+		if field.Optional || field.Nullable {
+			repetition = "optional"
+		}
+
+		switch {
+		case field.Type == "" && field.isArray:
+			writeParquetList(w, name, repetition, indent, func(indent string) {
+				fmt.Fprintf(w, "%srequired group element {\n", indent)
+				writeParquetGroup(w, field.Children, path+"[]", indent+"  ", opts)
+				fmt.Fprintf(w, "%s}\n", indent)
+			})
+		case field.Type == "":
+			fmt.Fprintf(w, "%s%s group %s {\n", indent, repetition, name)
+			writeParquetGroup(w, field.Children, path, indent+"  ", opts)
+			fmt.Fprintf(w, "%s}\n", indent)
+		default:
+			writeParquetLeaf(w, name, field.Type, repetition, indent, path, opts)
+		}
+	}
+}
+
+// parquetName rewrites key into a name the message schema syntax can hold,
+// replacing the whitespace and punctuation that delimit it and that Spark
+// rejects in column names: " ,;{}()=".
+func parquetName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || strings.ContainsRune(",;{}()=", r) {
+			return '_'
+		}
+		return r
+	}, key)
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// writeParquetList writes the standard three-level LIST structure, with
+// element writing the innermost element definition.
+func writeParquetList(w io.Writer, name, repetition, indent string, element func(indent string)) {
+	fmt.Fprintf(w, "%s%s group %s (LIST) {\n", indent, repetition, name)
+	fmt.Fprintf(w, "%s  repeated group list {\n", indent)
+	element(indent + "    ")
+	fmt.Fprintf(w, "%s  }\n", indent)
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+func writeParquetLeaf(w io.Writer, name, typ, repetition, indent, path string, opts *renderOptions) {
+	if strings.HasPrefix(typ, "array<") && strings.HasSuffix(typ, ">") {
+		elemType := typ[len("array<") : len(typ)-1]
+		writeParquetList(w, name, repetition, indent, func(indent string) {
+			writeParquetLeaf(w, "element", elemType, "required", indent, path+"[]", opts)
+		})
+		return
+	}
+
+	switch typ {
+	case "number":
+		fmt.Fprintf(w, "%s%s double %s;\n", indent, repetition, name)
+	case "boolean":
+		fmt.Fprintf(w, "%s%s boolean %s;\n", indent, repetition, name)
+	case "unknown":
+		opts.warn(path, "type unknown (only null seen) has no Parquet equivalent; written as optional binary (STRING)")
+		fmt.Fprintf(w, "%soptional binary %s (STRING);\n", indent, name)
+	case "string":
+		fmt.Fprintf(w, "%s%s binary %s (STRING);\n", indent, repetition, name)
+	default:
+		opts.warn(path, fmt.Sprintf("type %s has no Parquet equivalent; written as binary (STRING)", typ))
+		fmt.Fprintf(w, "%s%s binary %s (STRING);\n", indent, repetition, name)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteParquet(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{
+			"name":   "Alice",
+			"scores": []interface{}{1.0, 2.0},
+			"address": map[string]interface{}{
+				"city": "Ghent",
+			},
+			"tags": []interface{}{
+				map[string]interface{}{"id": 1.0},
+			},
+		},
+		map[string]interface{}{"name": "Bob", "active": true},
+	}
+
+	var buf bytes.Buffer
+	opts := &renderOptions{}
+	if err := writeParquet(&buf, analyzeJSON(data), opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", opts.warnings)
+	}
+
+	output := buf.String()
+	expectedLines := []string{
+		"message root {",
+		"  optional boolean active;",
+		"  optional group address {",
+		"    required binary city (STRING);",
+		"  required binary name (STRING);",
+		"  optional group scores (LIST) {",
+		"      required double element;",
+		"  optional group tags (LIST) {",
+		"    repeated group list {",
+		"      required group element {",
+		"        required double id;",
+	}
+
+	for _, line := range expectedLines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("output missing expected line: %q\nGot:\n%s", line, output)
+		}
+	}
+}
+
+func TestWriteParquetWarnings(t *testing.T) {
+	data := map[string]interface{}{
+		"avatar": nil,
+		"tags":   []interface{}{},
+	}
+
+	var buf bytes.Buffer
+	opts := &renderOptions{}
+	if err := writeParquet(&buf, analyzeJSON(data), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(opts.warnings) != 2 || !strings.HasPrefix(opts.warnings[0], "avatar: ") || !strings.HasPrefix(opts.warnings[1], "tags[]: ") {
+		t.Errorf("expected warnings for avatar and tags[], got %v", opts.warnings)
+	}
+	if !strings.Contains(buf.String(), "optional binary avatar (STRING);") {
+		t.Errorf("avatar should be an optional string column:\n%s", buf.String())
+	}
+}
+
+func TestWriteParquetNames(t *testing.T) {
+	data := map[string]interface{}{
+		"first name": "Alice",
+		"first_name": "Bob",
+		"tags(x)":    []interface{}{"a"},
+	}
+
+	var buf bytes.Buffer
+	opts := &renderOptions{}
+	if err := writeParquet(&buf, analyzeJSON(data), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`["first name"]: not a valid Parquet name; renamed to "first_name_2"`,
+		`tags(x): not a valid Parquet name; renamed to "tags_x_"`,
+	}
+	if strings.Join(opts.warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("warnings = %q; want %q", opts.warnings, expected)
+	}
+	for _, want := range []string{"required binary first_name (STRING);", "required binary first_name_2 (STRING);", "required group tags_x_ (LIST) {"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Field paths address a field of the shape, or a value of a document, by
+// its keys from the root, e.g. "user.address.city". Every command writes
+// and reads them in the same syntax:
+//
+//   - keys are joined with dots;
+//   - "[]" after a key stands for the elements of an array of objects, so
+//     the id of every tag is "tags[].id", and "[2]" for one element;
+//   - a key that is empty or holds a dot, bracket, quote, backslash, glob
+//     character ("*" or "?"), whitespace or control character is written
+//     as a JSON string in brackets, without the dot: `user["first.name"]`,
+//     `["*"]`. Other keys, including non-ASCII ones, are written as is.
+//
+// Filter patterns use the same syntax, in which bare "*", "?" and "**"
+// are wildcards and quoted keys are always literal.
+
+// pathSegment is one step of a path: a key, or with element set the
+// elements of an array, all of them when index is -1.
+type pathSegment struct {
+	key     string
+	quoted  bool // written in brackets, so never a wildcard
+	element bool
+	index   int
+}
+
+// fieldPath returns the path of key nested under parent, e.g. "user.id".
+func fieldPath(parent, key string) string {
+	switch {
+	case !isBareKey(key):
+		return parent + quoteKey(key)
+	case parent == "":
+		return key
+	}
+	return parent + "." + key
+}
+
+// childPath returns the path that children of field are nested under. The
+// elements of an array of objects are addressed with a "[]" suffix, so the id
+// of every tag is "tags[].id".
+func childPath(path string, field *FieldInfo) string {
+	if field.isArray {
+		return path + "[]"
+	}
+	return path
+}
+
+// isBareKey reports whether key can be written in a path without quotes.
+func isBareKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if strings.ContainsRune(`.[]"\*?`, r) || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteKey writes key as a bracketed JSON string: `["first.name"]`.
+func quoteKey(key string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(key)
+	return "[" + strings.TrimSuffix(buf.String(), "\n") + "]"
+}
+
+// parsePath splits a path into its segments. The empty path is the root.
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for i := 0; i < len(path); {
+		switch {
+		case path[i] == '[':
+			segment, n, err := parseBracket(path[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %v", path, err)
+			}
+			if segment.element && len(segments) == 0 {
+				return nil, fmt.Errorf("invalid path %q: %s must follow a key", path, path[i:i+n])
+			}
+			segments = append(segments, segment)
+			i += n
+		case path[i] == '.' && len(segments) == 0:
+			return nil, fmt.Errorf("invalid path %q: starts with a dot", path)
+		default:
+			if path[i] == '.' {
+				i++
+			}
+			end := i + strings.IndexAny(path[i:], ".[")
+			if end < i {
+				end = len(path)
+			}
+			key := path[i:end]
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key at offset %d (write it as [\"\"])", path, i)
+			}
+			if strings.ContainsAny(key, `]"\`) {
+				return nil, fmt.Errorf("invalid path %q: key %q must be quoted, as %s", path, key, quoteKey(key))
+			}
+			segments = append(segments, pathSegment{key: key})
+			i = end
+		}
+	}
+	return segments, nil
+}
+
+// parseBracket parses the bracketed segment at the start of s, returning it
+// with its length.
+func parseBracket(s string) (pathSegment, int, error) {
+	end := strings.IndexByte(s, ']')
+	switch {
+	case strings.HasPrefix(s, "[]"):
+		return pathSegment{element: true, index: -1}, 2, nil
+	case strings.HasPrefix(s, `["`):
+		// Find the closing quote, skipping escaped characters.
+		for i := 2; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				if i+1 >= len(s) || s[i+1] != ']' {
+					return pathSegment{}, 0, fmt.Errorf("quoted key %s is not followed by ]", s[1:i+1])
+				}
+				var key string
+				if err := json.Unmarshal([]byte(s[1:i+1]), &key); err != nil {
+					return pathSegment{}, 0, fmt.Errorf("quoted key %s: %v", s[1:i+1], err)
+				}
+				return pathSegment{key: key, quoted: true}, i + 2, nil
+			}
+		}
+		return pathSegment{}, 0, fmt.Errorf("unterminated quoted key %s", s)
+	case end > 1:
+		index, err := strconv.Atoi(s[1:end])
+		if err != nil || index < 0 {
+			return pathSegment{}, 0, fmt.Errorf("%s is neither [], an index nor a quoted key", s[:end+1])
+		}
+		return pathSegment{element: true, index: index}, end + 1, nil
+	}
+	return pathSegment{}, 0, fmt.Errorf("unterminated bracket %s", s)
+}
+
+// formatPath writes segments in canonical form.
+func formatPath(segments []pathSegment) string {
+	var path string
+	for _, s := range segments {
+		switch {
+		case !s.element:
+			path = fieldPath(path, s.key)
+		case s.index < 0:
+			path += "[]"
+		default:
+			path += "[" + strconv.Itoa(s.index) + "]"
+		}
+	}
+	return path
+}
+
+// canonicalPath rewrites path in canonical form, quoting only the keys that
+// need it: `["user"]["id"]` becomes "user.id".
+func canonicalPath(path string) (string, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return "", err
+	}
+	return formatPath(segments), nil
+}
+
+// keyPath rewrites path in canonical form without its "[]" segments, so that
+// paths naming the same field of the shape compare equal whether or not they
+// mark its arrays, as in filter patterns: "items[].id" and "items.id" both
+// become "items.id". The shape merges the elements of an array, so an
+// element index is an error.
+func keyPath(path string) (string, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return "", err
+	}
+	var keys []pathSegment
+	for _, s := range segments {
+		if !s.element {
+			keys = append(keys, s)
+		} else if s.index >= 0 {
+			return "", fmt.Errorf("%s: the shape merges the elements of arrays, write [] instead of [%d]", path, s.index)
+		}
+	}
+	return formatPath(keys), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFieldPath(t *testing.T) {
+	tests := []struct {
+		parent, key string
+		want        string
+	}{
+		{"", "user", "user"},
+		{"user", "id", "user.id"},
+		{"tags[]", "id", "tags[].id"},
+		{"", "first.name", `["first.name"]`},
+		{"user", "first.name", `user["first.name"]`},
+		{"tags[]", "display name", `tags[]["display name"]`},
+		{"", "", `[""]`},
+		{"a", "*", `a["*"]`},
+		{"a", `say "hi"`, `a["say \"hi\""]`},
+		{"a", "<b>", "a.<b>"},
+		{"Straße", "größe", "Straße.größe"},
+	}
+	for _, tt := range tests {
+		if got := fieldPath(tt.parent, tt.key); got != tt.want {
+			t.Errorf("fieldPath(%q, %q) = %s, want %s", tt.parent, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path      string
+		want      []pathSegment
+		canonical string
+	}{
+		{"", nil, ""},
+		{"user.id", []pathSegment{{key: "user"}, {key: "id"}}, "user.id"},
+		{"items[].id", []pathSegment{{key: "items"}, {element: true, index: -1}, {key: "id"}}, "items[].id"},
+		{"items[12]", []pathSegment{{key: "items"}, {element: true, index: 12}}, "items[12]"},
+		{`user["first.name"]`, []pathSegment{{key: "user"}, {key: "first.name", quoted: true}}, `user["first.name"]`},
+		{`["user"]["id"]`, []pathSegment{{key: "user", quoted: true}, {key: "id", quoted: true}}, "user.id"},
+		{`a["b]c"].d`, []pathSegment{{key: "a"}, {key: "b]c", quoted: true}, {key: "d"}}, `a["b]c"].d`},
+		{`["x\"y"]`, []pathSegment{{key: `x"y`, quoted: true}}, `["x\"y"]`},
+		{"**.debug", []pathSegment{{key: "**"}, {key: "debug"}}, `["**"].debug`},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if err != nil {
+			t.Errorf("parsePath(%s): %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%s) = %+v, want %+v", tt.path, got, tt.want)
+		}
+		if canonical := formatPath(got); canonical != tt.canonical {
+			t.Errorf("formatPath(parsePath(%s)) = %s, want %s", tt.path, canonical, tt.canonical)
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{".a", "starts with a dot"},
+		{"a..b", "empty key"},
+		{"a.", "empty key"},
+		{"[]", "must follow a key"},
+		{"a[x]", "neither [], an index nor a quoted key"},
+		{"a[", "unterminated bracket"},
+		{`a["b`, "unterminated quoted key"},
+		{`a["b"c]`, "is not followed by ]"},
+		{`a"b`, "must be quoted"},
+	}
+	for _, tt := range tests {
+		_, err := parsePath(tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("parsePath(%s) error = %v, want %q", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKeyPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"items[].id", "items.id"},
+		{"items.id", "items.id"},
+		{`["a.b"][][].c`, `["a.b"].c`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got, err := keyPath(tt.path); err != nil || got != tt.want {
+			t.Errorf("keyPath(%s) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+	if _, err := keyPath("items[2].id"); err == nil || !strings.Contains(err.Error(), "write [] instead of [2]") {
+		t.Errorf("keyPath(items[2].id) error = %v", err)
+	}
+}
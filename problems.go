@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// problemMediaType is the media type of RFC 9457 (formerly RFC 7807)
+// problem details.
+const problemMediaType = "application/problem+json"
+
+// problemMembers are the members RFC 9457 defines, with the JSON type each
+// must have when present.
+var problemMembers = []struct {
+	name, typ string
+}{
+	{"type", "string"},
+	{"title", "string"},
+	{"status", "number"},
+	{"detail", "string"},
+	{"instance", "string"},
+}
+
+// problemIssues returns the ways doc, a response body with HTTP status and
+// media type, falls short of a problem details object. Members are all
+// optional in the RFC, but a problem without a title or status is hard for
+// clients to act on, so their absence is reported too.
+func problemIssues(doc interface{}, status int, mediaType string) []string {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return []string{"body is not an object"}
+	}
+
+	var issues []string
+	members := 0
+	for _, m := range problemMembers {
+		value, ok := obj[m.name]
+		if !ok {
+			continue
+		}
+		members++
+		if typ := getType(value); typ != m.typ {
+			issues = append(issues, fmt.Sprintf("%q is %s, want %s", m.name, typ, m.typ))
+		}
+	}
+	if members == 0 {
+		return append(issues, "not problem details: none of type, title, status, detail or instance")
+	}
+
+	if mediaType != problemMediaType {
+		issues = append(issues, "sent as "+mediaType+" instead of "+problemMediaType)
+	}
+	if _, ok := obj["title"]; !ok {
+		issues = append(issues, `no "title"`)
+	}
+	switch s, ok := obj["status"].(float64); {
+	case !ok:
+		if _, present := obj["status"]; !present {
+			issues = append(issues, `no "status"`)
+		}
+	case int(s) != status:
+		issues = append(issues, fmt.Sprintf(`"status" %v does not match the response status %d`, s, status))
+	}
+	return issues
+}
+
+// isErrorResponse reports whether a response is one the problem audit
+// covers: an error status, or a body declared as problem details.
+func isErrorResponse(status int, mediaType string) bool {
+	return status >= 400 || mediaType == problemMediaType
+}
+
+// writeProblemAudit reports, for each route, status and media type of error
+// response, how many bodies are conforming problem details and what is wrong
+// with the others.
+func (c *captureRecorder) writeProblemAudit(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintln(w, "== error responses ==")
+	audited := false
+	for _, rt := range c.sortedRoutes() {
+		capture := c.routes[rt]
+		for _, status := range capture.statuses() {
+			bodies := capture.responsesOf(status)
+			for _, mediaType := range mediaTypes(bodies) {
+				if !isErrorResponse(status, mediaType) {
+					continue
+				}
+				audited = true
+				docs := bodies[mediaType]
+				counts := make(map[string]int)
+				failing := 0
+				for _, doc := range docs {
+					issues := problemIssues(doc, status, mediaType)
+					if len(issues) > 0 {
+						failing++
+					}
+					for _, issue := range issues {
+						counts[issue]++
+					}
+				}
+
+				if failing == 0 {
+					fmt.Fprintf(w, "%s %d %s: %d of %d bodies conform\n", rt, status, mediaType, len(docs), len(docs))
+					continue
+				}
+				fmt.Fprintf(w, "%s %d %s: %d of %d bodies do not conform\n", rt, status, mediaType, failing, len(docs))
+				issues := make([]string, 0, len(counts))
+				for issue := range counts {
+					issues = append(issues, issue)
+				}
+				sort.Strings(issues)
+				for _, issue := range issues {
+					fmt.Fprintf(w, "  - %s (%d)\n", issue, counts[issue])
+				}
+			}
+		}
+	}
+	if !audited {
+		fmt.Fprintln(w, "no JSON error responses captured")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestProblemIssues(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       interface{}
+		status    int
+		mediaType string
+		want      []string
+	}{
+		{
+			"conforming",
+			map[string]interface{}{"type": "https://example.com/probs/out-of-credit", "title": "Out of credit", "status": 403.0, "detail": "Balance is 30", "instance": "/account/12345"},
+			403, problemMediaType, nil,
+		},
+		{
+			"extension members",
+			map[string]interface{}{"title": "Invalid", "status": 422.0, "errors": []interface{}{"name"}},
+			422, problemMediaType, nil,
+		},
+		{
+			"custom envelope",
+			map[string]interface{}{"error": "taken"},
+			409, "application/json", []string{"not problem details: none of type, title, status, detail or instance"},
+		},
+		{
+			"wrong media type and member types",
+			map[string]interface{}{"title": "Not Found", "status": "404"},
+			404, "application/json", []string{`"status" is string, want number`, "sent as application/json instead of application/problem+json"},
+		},
+		{
+			"status mismatch and missing title",
+			map[string]interface{}{"type": "about:blank", "status": 400.0},
+			500, problemMediaType, []string{`no "title"`, `"status" 400 does not match the response status 500`},
+		},
+		{
+			"missing status",
+			map[string]interface{}{"title": "Gone"},
+			410, problemMediaType, []string{`no "status"`},
+		},
+		{"array body", []interface{}{}, 400, "application/json", []string{"body is not an object"}},
+	}
+	for _, tt := range tests {
+		if got := problemIssues(tt.doc, tt.status, tt.mediaType); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: problemIssues() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteProblemAudit(t *testing.T) {
+	rec := newCaptureRecorder()
+	plain := http.Header{"Content-Type": {"application/json"}}
+	problem := http.Header{"Content-Type": {problemMediaType}}
+	rec.record(rec.routeFor("GET", "/users/1"), plain, []byte(`{"id": 1}`), http.StatusOK)
+	rec.record(rec.routeFor("GET", "/users/2"), problem, []byte(`{"title": "Not Found", "status": 404}`), http.StatusNotFound)
+	rec.record(rec.routeFor("POST", "/users"), plain, []byte(`{"error": "taken"} {"error": "bad"}`), http.StatusConflict)
+
+	var buf bytes.Buffer
+	rec.writeProblemAudit(&buf)
+	expected := "== error responses ==\n" +
+		"POST /users 409 application/json: 2 of 2 bodies do not conform\n" +
+		"  - not problem details: none of type, title, status, detail or instance (2)\n" +
+		"GET /users/{id} 404 application/problem+json: 1 of 1 bodies conform\n"
+	if buf.String() != expected {
+		t.Errorf("writeProblemAudit() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	newCaptureRecorder().writeProblemAudit(&buf)
+	if buf.String() != "== error responses ==\nno JSON error responses captured\n" {
+		t.Errorf("unexpected output without errors:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,736 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var errProtoTruncated = errors.New("truncated message")
+
+// Field types and labels from google/protobuf/descriptor.proto.
+const (
+	protoDouble   = 1
+	protoFloat    = 2
+	protoInt64    = 3
+	protoUint64   = 4
+	protoInt32    = 5
+	protoFixed64  = 6
+	protoFixed32  = 7
+	protoBool     = 8
+	protoString   = 9
+	protoGroup    = 10
+	protoMessage  = 11
+	protoBytes    = 12
+	protoUint32   = 13
+	protoEnum     = 14
+	protoSfixed32 = 15
+	protoSfixed64 = 16
+	protoSint32   = 17
+	protoSint64   = 18
+
+	protoRepeated = 3
+)
+
+// protoDescriptor is one message type of a descriptor set.
+type protoDescriptor struct {
+	name     string // fully qualified, without the leading dot
+	fields   map[uint64]*protoField
+	mapEntry bool
+}
+
+type protoField struct {
+	name     string
+	jsonName string
+	label    uint64
+	typ      uint64
+	typeName string // fully qualified message or enum name
+}
+
+// protoRegistry holds the message and enum types of a descriptor set.
+type protoRegistry struct {
+	messages map[string]*protoDescriptor
+	enums    map[string]map[int32]string
+}
+
+// protoDecoder decodes binary protobuf records into their canonical proto3
+// JSON mapping, using the message types of a descriptor set produced by
+// protoc --descriptor_set_out. Records are length-delimited (each preceded
+// by its size as a varint) unless the input is a single message.
+type protoDecoder struct {
+	r    *bufio.Reader
+	opts decodeOptions
+	reg  *protoRegistry
+	msg  *protoDescriptor
+	done bool
+}
+
+func newProtoDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	d := &protoDecoder{r: newBufferedReader(r)}
+	if opts != nil {
+		d.opts = *opts
+	}
+	return d
+}
+
+func (d *protoDecoder) Decode() (interface{}, error) {
+	if d.reg == nil {
+		if err := d.load(); err != nil {
+			return nil, fmt.Errorf("protobuf: %v", err)
+		}
+	}
+
+	var buf []byte
+	if d.opts.protoSingle {
+		if d.done {
+			return nil, io.EOF
+		}
+		d.done = true
+		data, err := io.ReadAll(d.r)
+		if err != nil {
+			return nil, err
+		}
+		buf = data
+	} else {
+		if _, err := d.r.Peek(1); err != nil {
+			return nil, err
+		}
+		n, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return nil, fmt.Errorf("protobuf: %w", unexpectedEOF(err))
+		}
+		if buf, err = readBytes(d.r, n); err != nil {
+			return nil, fmt.Errorf("protobuf: %w", err)
+		}
+	}
+
+	v, err := d.reg.decode(d.msg, buf, 0)
+	if err != nil {
+		return nil, fmt.Errorf("protobuf: %s: %w", d.msg.name, err)
+	}
+	return v, nil
+}
+
+// load reads the descriptor set and looks up the record message type. The
+// type may be omitted when the set defines only one message.
+func (d *protoDecoder) load() error {
+	if d.opts.protoDescriptor == "" {
+		return fmt.Errorf("--proto-descriptor is required")
+	}
+	data, err := os.ReadFile(d.opts.protoDescriptor)
+	if err != nil {
+		return err
+	}
+	reg, err := parseDescriptorSet(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", d.opts.protoDescriptor, err)
+	}
+
+	name := strings.TrimPrefix(d.opts.protoMessage, ".")
+	if name == "" {
+		var names []string
+		for n, m := range reg.messages {
+			if !m.mapEntry {
+				names = append(names, n)
+			}
+		}
+		if len(names) != 1 {
+			return fmt.Errorf("--proto-message is required when the descriptor set has %d messages", len(names))
+		}
+		name = names[0]
+	}
+	msg, ok := reg.messages[name]
+	if !ok {
+		return fmt.Errorf("message %q not found in %s", name, d.opts.protoDescriptor)
+	}
+	d.reg, d.msg = reg, msg
+	return nil
+}
+
+// protoBuffer reads protobuf wire format from an in-memory message.
+type protoBuffer struct {
+	buf []byte
+	pos int
+}
+
+func (b *protoBuffer) varint() (uint64, error) {
+	v, n := binary.Uvarint(b.buf[b.pos:])
+	if n <= 0 {
+		return 0, errProtoTruncated
+	}
+	b.pos += n
+	return v, nil
+}
+
+func (b *protoBuffer) next(n uint64) ([]byte, error) {
+	if n > uint64(len(b.buf)-b.pos) {
+		return nil, errProtoTruncated
+	}
+	p := b.buf[b.pos : b.pos+int(n)]
+	b.pos += int(n)
+	return p, nil
+}
+
+// field reads the next tag and returns the field number, wire type and raw
+// value: the varint for wire type 0, the little-endian bits for types 1 and
+// 5, and the payload for type 2 and for groups.
+func (b *protoBuffer) field(depth int) (num uint64, wire int, raw uint64, payload []byte, err error) {
+	tag, err := b.varint()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	num, wire = tag>>3, int(tag&7)
+	switch wire {
+	case 0:
+		raw, err = b.varint()
+	case 1:
+		var p []byte
+		if p, err = b.next(8); err == nil {
+			raw = binary.LittleEndian.Uint64(p)
+		}
+	case 5:
+		var p []byte
+		if p, err = b.next(4); err == nil {
+			raw = uint64(binary.LittleEndian.Uint32(p))
+		}
+	case 2:
+		var n uint64
+		if n, err = b.varint(); err == nil {
+			payload, err = b.next(n)
+		}
+	case 3:
+		payload, err = b.group(num, depth)
+	default:
+		err = fmt.Errorf("invalid wire type %d", wire)
+	}
+	return num, wire, raw, payload, err
+}
+
+// group returns the fields of a group up to its end tag, consuming the tag.
+func (b *protoBuffer) group(num uint64, depth int) ([]byte, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("nesting exceeds %d levels", maxDecodeDepth)
+	}
+	start := b.pos
+	for {
+		end := b.pos
+		tag, err := b.varint()
+		if err != nil {
+			return nil, err
+		}
+		if tag == num<<3|4 {
+			return b.buf[start:end], nil
+		}
+		b.pos = end
+		if _, _, _, _, err := b.field(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseDescriptorSet reads a serialized google.protobuf.FileDescriptorSet.
+func parseDescriptorSet(data []byte) (*protoRegistry, error) {
+	reg := &protoRegistry{
+		messages: make(map[string]*protoDescriptor),
+		enums:    make(map[string]map[int32]string),
+	}
+	b := &protoBuffer{buf: data}
+	for b.pos < len(b.buf) {
+		num, _, _, file, err := b.field(0)
+		if err != nil {
+			return nil, err
+		}
+		if num != 1 {
+			continue
+		}
+
+		var pkg string
+		var messages, enums [][]byte
+		f := &protoBuffer{buf: file}
+		for f.pos < len(f.buf) {
+			num, _, _, payload, err := f.field(0)
+			if err != nil {
+				return nil, err
+			}
+			switch num {
+			case 2:
+				pkg = string(payload)
+			case 4:
+				messages = append(messages, payload)
+			case 5:
+				enums = append(enums, payload)
+			}
+		}
+		for _, m := range messages {
+			if err := reg.addMessage(pkg, m); err != nil {
+				return nil, err
+			}
+		}
+		for _, e := range enums {
+			if err := reg.addEnum(pkg, e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return reg, nil
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// addMessage registers a DescriptorProto and its nested types under scope.
+func (r *protoRegistry) addMessage(scope string, data []byte) error {
+	msg := &protoDescriptor{fields: make(map[uint64]*protoField)}
+	var nested, enums [][]byte
+	b := &protoBuffer{buf: data}
+	for b.pos < len(b.buf) {
+		num, _, _, payload, err := b.field(0)
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			msg.name = qualify(scope, string(payload))
+		case 2:
+			field, number, err := parseFieldDescriptor(payload)
+			if err != nil {
+				return err
+			}
+			msg.fields[number] = field
+		case 3:
+			nested = append(nested, payload)
+		case 4:
+			enums = append(enums, payload)
+		case 7:
+			opts := &protoBuffer{buf: payload}
+			for opts.pos < len(opts.buf) {
+				num, _, raw, _, err := opts.field(0)
+				if err != nil {
+					return err
+				}
+				if num == 7 {
+					msg.mapEntry = raw != 0
+				}
+			}
+		}
+	}
+
+	r.messages[msg.name] = msg
+	for _, m := range nested {
+		if err := r.addMessage(msg.name, m); err != nil {
+			return err
+		}
+	}
+	for _, e := range enums {
+		if err := r.addEnum(msg.name, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseFieldDescriptor(data []byte) (*protoField, uint64, error) {
+	field := &protoField{}
+	var number uint64
+	b := &protoBuffer{buf: data}
+	for b.pos < len(b.buf) {
+		num, _, raw, payload, err := b.field(0)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch num {
+		case 1:
+			field.name = string(payload)
+		case 3:
+			number = raw
+		case 4:
+			field.label = raw
+		case 5:
+			field.typ = raw
+		case 6:
+			field.typeName = strings.TrimPrefix(string(payload), ".")
+		case 10:
+			field.jsonName = string(payload)
+		}
+	}
+	if field.jsonName == "" {
+		field.jsonName = protoJSONName(field.name)
+	}
+	return field, number, nil
+}
+
+func (r *protoRegistry) addEnum(scope string, data []byte) error {
+	var name string
+	values := make(map[int32]string)
+	b := &protoBuffer{buf: data}
+	for b.pos < len(b.buf) {
+		num, _, _, payload, err := b.field(0)
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			name = string(payload)
+		case 2:
+			var valueName string
+			var number int32
+			v := &protoBuffer{buf: payload}
+			for v.pos < len(v.buf) {
+				num, _, raw, payload, err := v.field(0)
+				if err != nil {
+					return err
+				}
+				switch num {
+				case 1:
+					valueName = string(payload)
+				case 2:
+					number = int32(raw)
+				}
+			}
+			if _, ok := values[number]; !ok {
+				values[number] = valueName
+			}
+		}
+	}
+	r.enums[qualify(scope, name)] = values
+	return nil
+}
+
+// protoJSONName converts a field name to lowerCamelCase the way protoc
+// does when a descriptor has no json_name.
+func protoJSONName(name string) string {
+	var sb strings.Builder
+	upper := false
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			c = unicode.ToUpper(c)
+			upper = false
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// decode converts a serialized message to its JSON mapping. Fields absent
+// from the wire are left out, as proto3 JSON printers do for defaults.
+func (r *protoRegistry) decode(msg *protoDescriptor, data []byte, depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("nesting exceeds %d levels", maxDecodeDepth)
+	}
+	if msg.name == "google.protobuf.Any" {
+		return r.decodeAny(msg, data, depth)
+	}
+
+	obj := make(map[string]interface{})
+	b := &protoBuffer{buf: data}
+	for b.pos < len(b.buf) {
+		num, wire, raw, payload, err := b.field(depth)
+		if err != nil {
+			return nil, err
+		}
+		field, ok := msg.fields[num]
+		if !ok {
+			continue
+		}
+
+		if field.label != protoRepeated {
+			v, err := r.value(field, wire, raw, payload, depth)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field.name, err)
+			}
+			obj[field.jsonName] = v
+			continue
+		}
+
+		if entry := r.messages[field.typeName]; field.typ == protoMessage && entry != nil && entry.mapEntry {
+			m, _ := obj[field.jsonName].(map[string]interface{})
+			if m == nil {
+				m = make(map[string]interface{})
+			}
+			if err := r.mapEntry(entry, payload, m, depth); err != nil {
+				return nil, fmt.Errorf("%s: %w", field.name, err)
+			}
+			obj[field.jsonName] = m
+			continue
+		}
+
+		arr, _ := obj[field.jsonName].([]interface{})
+		if arr == nil {
+			arr = []interface{}{}
+		}
+		if wire == 2 && isPackable(field.typ) {
+			// Packed scalars: the payload holds consecutive values.
+			p := &protoBuffer{buf: payload}
+			for p.pos < len(p.buf) {
+				raw, err := p.packed(field.typ)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", field.name, err)
+				}
+				v, err := r.value(field, packedWireType(field.typ), raw, nil, depth)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", field.name, err)
+				}
+				arr = append(arr, v)
+			}
+		} else {
+			v, err := r.value(field, wire, raw, payload, depth)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field.name, err)
+			}
+			arr = append(arr, v)
+		}
+		obj[field.jsonName] = arr
+	}
+	return r.wellKnownJSON(msg, obj), nil
+}
+
+func (r *protoRegistry) mapEntry(entry *protoDescriptor, data []byte, m map[string]interface{}, depth int) error {
+	keyField, valueField := entry.fields[1], entry.fields[2]
+	if keyField == nil || valueField == nil {
+		return fmt.Errorf("map entry %s lacks a key or value field", entry.name)
+	}
+	kv, err := r.decode(entry, data, depth+1)
+	if err != nil {
+		return err
+	}
+	obj := kv.(map[string]interface{})
+	key := mapKey(obj[keyField.jsonName])
+	if _, ok := obj[keyField.jsonName]; !ok {
+		key = mapKey(r.protoDefault(keyField))
+	}
+	value, ok := obj[valueField.jsonName]
+	if !ok {
+		value = r.protoDefault(valueField)
+	}
+	m[key] = value
+	return nil
+}
+
+// decodeAny expands an Any whose type is in the descriptor set into the
+// embedded message with an "@type" member.
+func (r *protoRegistry) decodeAny(msg *protoDescriptor, data []byte, depth int) (interface{}, error) {
+	var typeURL string
+	var value []byte
+	b := &protoBuffer{buf: data}
+	for b.pos < len(b.buf) {
+		num, _, _, payload, err := b.field(depth)
+		if err != nil {
+			return nil, err
+		}
+		switch num {
+		case 1:
+			typeURL = string(payload)
+		case 2:
+			value = payload
+		}
+	}
+
+	obj := map[string]interface{}{"@type": typeURL}
+	embedded, ok := r.messages[typeURL[strings.LastIndex(typeURL, "/")+1:]]
+	if !ok {
+		obj["value"] = base64.StdEncoding.EncodeToString(value)
+		return obj, nil
+	}
+	v, err := r.decode(embedded, value, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	if fields, ok := v.(map[string]interface{}); ok && !isWellKnown(embedded.name) {
+		for k, fv := range fields {
+			obj[k] = fv
+		}
+	} else {
+		obj["value"] = v
+	}
+	return obj, nil
+}
+
+// value converts one wire value of field to JSON. 64-bit integers become
+// strings, as in the canonical mapping; enums become value names.
+func (r *protoRegistry) value(field *protoField, wire int, raw uint64, payload []byte, depth int) (interface{}, error) {
+	want := 0
+	switch field.typ {
+	case protoDouble, protoFixed64, protoSfixed64:
+		want = 1
+	case protoFloat, protoFixed32, protoSfixed32:
+		want = 5
+	case protoString, protoBytes, protoMessage:
+		want = 2
+	case protoGroup:
+		want = 3
+	}
+	if wire != want {
+		return nil, fmt.Errorf("wire type %d does not match field type %d", wire, field.typ)
+	}
+
+	switch field.typ {
+	case protoDouble:
+		return floatValue(math.Float64frombits(raw)), nil
+	case protoFloat:
+		return floatValue(float64(math.Float32frombits(uint32(raw)))), nil
+	case protoInt64, protoSfixed64:
+		return strconv.FormatInt(int64(raw), 10), nil
+	case protoUint64, protoFixed64:
+		return strconv.FormatUint(raw, 10), nil
+	case protoSint64:
+		return strconv.FormatInt(int64(raw>>1)^-int64(raw&1), 10), nil
+	case protoInt32, protoSfixed32:
+		return float64(int32(raw)), nil
+	case protoUint32, protoFixed32:
+		return float64(uint32(raw)), nil
+	case protoSint32:
+		return float64(int32(uint32(raw)>>1) ^ -int32(raw&1)), nil
+	case protoBool:
+		return raw != 0, nil
+	case protoString:
+		return string(payload), nil
+	case protoBytes:
+		return base64.StdEncoding.EncodeToString(payload), nil
+	case protoEnum:
+		if name, ok := r.enums[field.typeName][int32(raw)]; ok {
+			if field.typeName == "google.protobuf.NullValue" {
+				return nil, nil
+			}
+			return name, nil
+		}
+		return float64(int32(raw)), nil
+	case protoMessage, protoGroup:
+		msg, ok := r.messages[field.typeName]
+		if !ok {
+			return nil, fmt.Errorf("message type %q not in descriptor set", field.typeName)
+		}
+		return r.decode(msg, payload, depth+1)
+	}
+	return nil, fmt.Errorf("unsupported field type %d", field.typ)
+}
+
+func isPackable(typ uint64) bool {
+	return typ != protoString && typ != protoBytes && typ != protoMessage && typ != protoGroup
+}
+
+func packedWireType(typ uint64) int {
+	switch typ {
+	case protoDouble, protoFixed64, protoSfixed64:
+		return 1
+	case protoFloat, protoFixed32, protoSfixed32:
+		return 5
+	}
+	return 0
+}
+
+// packed reads one element of a packed repeated field.
+func (b *protoBuffer) packed(typ uint64) (uint64, error) {
+	switch packedWireType(typ) {
+	case 1:
+		p, err := b.next(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint64(p), nil
+	case 5:
+		p, err := b.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint32(p)), nil
+	}
+	return b.varint()
+}
+
+// protoDefault returns the JSON value of a field left at its default.
+func (r *protoRegistry) protoDefault(field *protoField) interface{} {
+	switch field.typ {
+	case protoInt64, protoUint64, protoSint64, protoFixed64, protoSfixed64:
+		return "0"
+	case protoBool:
+		return false
+	case protoString, protoBytes:
+		return ""
+	case protoMessage, protoGroup:
+		return map[string]interface{}{}
+	case protoEnum:
+		if name, ok := r.enums[field.typeName][0]; ok {
+			return name
+		}
+	}
+	return 0.0
+}
+
+func isWellKnown(name string) bool {
+	switch name {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration", "google.protobuf.FieldMask",
+		"google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue",
+		"google.protobuf.DoubleValue", "google.protobuf.FloatValue", "google.protobuf.Int64Value",
+		"google.protobuf.UInt64Value", "google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
+
+// wellKnownJSON applies the special JSON forms of the google.protobuf
+// well-known types to their decoded fields.
+func (r *protoRegistry) wellKnownJSON(msg *protoDescriptor, obj map[string]interface{}) interface{} {
+	if !isWellKnown(msg.name) {
+		return obj
+	}
+	switch name := strings.TrimPrefix(msg.name, "google.protobuf."); name {
+	case "Timestamp":
+		seconds, _ := strconv.ParseInt(fmt.Sprint(obj["seconds"]), 10, 64)
+		nanos, _ := obj["nanos"].(float64)
+		return time.Unix(seconds, int64(nanos)).UTC().Format(time.RFC3339Nano)
+	case "Duration":
+		seconds, _ := strconv.ParseInt(fmt.Sprint(obj["seconds"]), 10, 64)
+		nanos, _ := obj["nanos"].(float64)
+		d := time.Duration(seconds)*time.Second + time.Duration(nanos)
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+	case "FieldMask":
+		paths, _ := obj["paths"].([]interface{})
+		parts := make([]string, len(paths))
+		for i, p := range paths {
+			parts[i] = protoJSONName(fmt.Sprint(p))
+		}
+		return strings.Join(parts, ",")
+	case "Struct":
+		if fields, ok := obj["fields"].(map[string]interface{}); ok {
+			return fields
+		}
+		return map[string]interface{}{}
+	case "ListValue":
+		if values, ok := obj["values"].([]interface{}); ok {
+			return values
+		}
+		return []interface{}{}
+	case "Value":
+		for _, v := range obj {
+			return v
+		}
+		return nil
+	default:
+		// Wrapper types are their value, which may be left at its default.
+		if v, ok := obj["value"]; ok {
+			return v
+		}
+		if field := msg.fields[1]; field != nil {
+			return r.protoDefault(field)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Helpers for hand-encoding protobuf messages.
+
+func pbVarint(v uint64) []byte {
+	return binary.AppendUvarint(nil, v)
+}
+
+func pbTag(num, wire int) []byte {
+	return pbVarint(uint64(num<<3 | wire))
+}
+
+func pbUint(num int, v uint64) []byte {
+	return append(pbTag(num, 0), pbVarint(v)...)
+}
+
+func pbBytes(num int, parts ...[]byte) []byte {
+	payload := bytes.Join(parts, nil)
+	return append(append(pbTag(num, 2), pbVarint(uint64(len(payload)))...), payload...)
+}
+
+func pbString(num int, s string) []byte {
+	return pbBytes(num, []byte(s))
+}
+
+func pbDouble(num int, f float64) []byte {
+	return binary.LittleEndian.AppendUint64(pbTag(num, 1), math.Float64bits(f))
+}
+
+// pbFieldDesc encodes a FieldDescriptorProto.
+func pbFieldDesc(name string, number, label, typ int, typeName string) []byte {
+	parts := [][]byte{pbString(1, name), pbUint(3, uint64(number)), pbUint(4, uint64(label)), pbUint(5, uint64(typ))}
+	if typeName != "" {
+		parts = append(parts, pbString(6, typeName))
+	}
+	parts = append(parts, pbString(10, protoJSONName(name)))
+	return pbBytes(2, parts...)
+}
+
+// testDescriptorSet describes:
+//
+//	package shop;
+//	message Order {
+//	  enum Status { UNKNOWN = 0; PAID = 1; }
+//	  message Item { string sku = 1; }
+//	  string order_id = 1;
+//	  int64 total_cents = 2;
+//	  repeated Item items = 3;
+//	  map<string, int32> counts = 4;
+//	  Status status = 5;
+//	  google.protobuf.Timestamp created_at = 6;
+//	  repeated int32 codes = 7;
+//	  double ratio = 8;
+//	  bytes blob = 9;
+//	  sint32 delta = 10;
+//	}
+func testDescriptorSet() []byte {
+	timestamp := pbBytes(1,
+		pbString(1, "google/protobuf/timestamp.proto"),
+		pbString(2, "google.protobuf"),
+		pbBytes(4,
+			pbString(1, "Timestamp"),
+			pbFieldDesc("seconds", 1, 1, protoInt64, ""),
+			pbFieldDesc("nanos", 2, 1, protoInt32, ""),
+		),
+	)
+	order := pbBytes(1,
+		pbString(1, "shop.proto"),
+		pbString(2, "shop"),
+		pbBytes(4,
+			pbString(1, "Order"),
+			pbFieldDesc("order_id", 1, 1, protoString, ""),
+			pbFieldDesc("total_cents", 2, 1, protoInt64, ""),
+			pbFieldDesc("items", 3, 3, protoMessage, ".shop.Order.Item"),
+			pbFieldDesc("counts", 4, 3, protoMessage, ".shop.Order.CountsEntry"),
+			pbFieldDesc("status", 5, 1, protoEnum, ".shop.Order.Status"),
+			pbFieldDesc("created_at", 6, 1, protoMessage, ".google.protobuf.Timestamp"),
+			pbFieldDesc("codes", 7, 3, protoInt32, ""),
+			pbFieldDesc("ratio", 8, 1, protoDouble, ""),
+			pbFieldDesc("blob", 9, 1, protoBytes, ""),
+			pbFieldDesc("delta", 10, 1, protoSint32, ""),
+			pbBytes(3, pbString(1, "Item"), pbFieldDesc("sku", 1, 1, protoString, "")),
+			pbBytes(3,
+				pbString(1, "CountsEntry"),
+				pbFieldDesc("key", 1, 1, protoString, ""),
+				pbFieldDesc("value", 2, 1, protoInt32, ""),
+				pbBytes(7, pbUint(7, 1)),
+			),
+			pbBytes(4,
+				pbString(1, "Status"),
+				pbBytes(2, pbString(1, "UNKNOWN"), pbUint(2, 0)),
+				pbBytes(2, pbString(1, "PAID"), pbUint(2, 1)),
+			),
+		),
+	)
+	return append(timestamp, order...)
+}
+
+func writeDescriptorSet(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shop.pb")
+	if err := os.WriteFile(path, testDescriptorSet(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func delimited(messages ...[]byte) []byte {
+	var out []byte
+	for _, m := range messages {
+		out = append(out, pbVarint(uint64(len(m)))...)
+		out = append(out, m...)
+	}
+	return out
+}
+
+func TestProtoDecoder(t *testing.T) {
+	order := bytes.Join([][]byte{
+		pbString(1, "o-1"),
+		pbUint(2, 1999),
+		pbBytes(3, pbString(1, "A1")),
+		pbBytes(3, pbString(1, "B2")),
+		pbBytes(4, pbString(1, "red"), pbUint(2, 2)),
+		pbBytes(4, pbString(1, "blue")),
+		pbUint(5, 1),
+		pbBytes(6, pbUint(1, 1700000000), pbUint(2, 500000000)),
+		pbBytes(7, pbVarint(3), pbVarint(4)),
+		pbUint(7, 5),
+		pbDouble(8, 0.5),
+		pbString(9, "\x01\x02"),
+		pbUint(10, 3),
+		pbUint(99, 7), // unknown fields are skipped
+	}, nil)
+	input := delimited(order, pbString(1, "o-2"))
+
+	opts := &decodeOptions{protoDescriptor: writeDescriptorSet(t), protoMessage: ".shop.Order"}
+	docs, err := decodeAll(newProtoDecoder(bytes.NewReader(input), opts))
+	if err != nil {
+		t.Fatalf("decodeAll() error: %v", err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{
+			"orderId":    "o-1",
+			"totalCents": "1999",
+			"items":      []interface{}{map[string]interface{}{"sku": "A1"}, map[string]interface{}{"sku": "B2"}},
+			"counts":     map[string]interface{}{"red": 2.0, "blue": 0.0},
+			"status":     "PAID",
+			"createdAt":  "2023-11-14T22:13:20.5Z",
+			"codes":      []interface{}{3.0, 4.0, 5.0},
+			"ratio":      0.5,
+			"blob":       "AQI=",
+			"delta":      -2.0,
+		},
+		map[string]interface{}{"orderId": "o-2"},
+	}
+	if !reflect.DeepEqual(docs, expected) {
+		t.Errorf("decodeAll() = %#v; want %#v", docs, expected)
+	}
+}
+
+func TestProtoDecoderSingleMessage(t *testing.T) {
+	opts := &decodeOptions{protoDescriptor: writeDescriptorSet(t), protoMessage: "shop.Order.Item", protoSingle: true}
+	docs, err := decodeAll(newProtoDecoder(bytes.NewReader(pbString(1, "A1")), opts))
+	if err != nil {
+		t.Fatalf("decodeAll() error: %v", err)
+	}
+	if !reflect.DeepEqual(docs, []interface{}{map[string]interface{}{"sku": "A1"}}) {
+		t.Errorf("decodeAll() = %#v", docs)
+	}
+}
+
+func TestProtoDecoderErrors(t *testing.T) {
+	descriptor := writeDescriptorSet(t)
+	tests := []struct {
+		name    string
+		opts    *decodeOptions
+		input   []byte
+		wantErr string
+	}{
+		{"no descriptor", &decodeOptions{}, delimited(nil), "--proto-descriptor is required"},
+		{"ambiguous message", &decodeOptions{protoDescriptor: descriptor}, delimited(nil), "--proto-message is required"},
+		{"unknown message", &decodeOptions{protoDescriptor: descriptor, protoMessage: "shop.Refund"}, delimited(nil), "not found"},
+		{"truncated record", &decodeOptions{protoDescriptor: descriptor, protoMessage: "shop.Order"}, []byte{5, 0x0a}, "unexpected EOF"},
+		{"wrong wire type", &decodeOptions{protoDescriptor: descriptor, protoMessage: "shop.Order"}, delimited(pbUint(1, 1)), "order_id: wire type 0"},
+	}
+
+	for _, tt := range tests {
+		_, err := decodeAll(newProtoDecoder(bytes.NewReader(tt.input), tt.opts))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v; want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProtoDecoderMalformedMapEntry(t *testing.T) {
+	// A map entry without its value field, as no protoc would write.
+	set := pbBytes(1,
+		pbString(1, "bag.proto"),
+		pbString(2, "bag"),
+		pbBytes(4,
+			pbString(1, "Bag"),
+			pbFieldDesc("tags", 1, 3, protoMessage, ".bag.Bag.TagsEntry"),
+			pbBytes(3,
+				pbString(1, "TagsEntry"),
+				pbFieldDesc("key", 1, 1, protoString, ""),
+				pbBytes(7, pbUint(7, 1)),
+			),
+		),
+	)
+	path := filepath.Join(t.TempDir(), "bag.pb")
+	if err := os.WriteFile(path, set, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &decodeOptions{protoDescriptor: path, protoMessage: "bag.Bag"}
+	_, err := decodeAll(newProtoDecoder(bytes.NewReader(delimited(pbBytes(1, pbString(1, "k")))), opts))
+	if err == nil || !strings.Contains(err.Error(), "map entry bag.Bag.TagsEntry lacks a key or value field") {
+		t.Errorf("error = %v; want the malformed map entry reported", err)
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Limits on what the proxy keeps for analysis. Larger bodies and bodies past
+// the sample limit are still forwarded, just not recorded.
+const (
+	maxCaptureBytes = 10 << 20
+	maxRouteSamples = 1000
+)
+
+// route identifies the requests whose bodies are shaped together.
+type route struct {
+	method string
+	path   string
+}
+
+func (r route) String() string {
+	return r.method + " " + r.path
+}
+
+// routeCapture holds what was seen on one route: the JSON request bodies by
+// media type, the JSON response bodies by status code and media type, and
+// the query parameters and notable headers of each request. Bodies of
+// different media types, such as application/json results and
+// application/problem+json errors, are shaped separately.
+type routeCapture struct {
+	requests  map[string][]interface{}
+	responses map[responseKey][]interface{}
+	queries   []interface{}
+	headers   []interface{}
+}
+
+type responseKey struct {
+	status    int
+	mediaType string
+}
+
+// statuses returns the status codes of the responses recorded, in order.
+func (c *routeCapture) statuses() []int {
+	var statuses []int
+	for key := range c.responses {
+		if !slices.Contains(statuses, key.status) {
+			statuses = append(statuses, key.status)
+		}
+	}
+	sort.Ints(statuses)
+	return statuses
+}
+
+// responsesOf returns the response bodies with status, by media type.
+func (c *routeCapture) responsesOf(status int) map[string][]interface{} {
+	bodies := make(map[string][]interface{})
+	for key, docs := range c.responses {
+		if key.status == status {
+			bodies[key.mediaType] = docs
+		}
+	}
+	return bodies
+}
+
+// responsesByType returns the response bodies of every status, by media
+// type.
+func (c *routeCapture) responsesByType() map[string][]interface{} {
+	bodies := make(map[string][]interface{})
+	for _, status := range c.statuses() {
+		for mediaType, docs := range c.responsesOf(status) {
+			bodies[mediaType] = append(bodies[mediaType], docs...)
+		}
+	}
+	return bodies
+}
+
+// mediaTypes returns the media types of bodies in order.
+func mediaTypes(bodies map[string][]interface{}) []string {
+	types := make([]string, 0, len(bodies))
+	for mediaType := range bodies {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// captureRecorder accumulates the JSON bodies passing through the proxy,
+// per route.
+type captureRecorder struct {
+	mu       sync.Mutex
+	routes   map[route]*routeCapture
+	rawPaths bool // key routes by concrete path instead of template
+}
+
+func newCaptureRecorder() *captureRecorder {
+	return &captureRecorder{routes: make(map[route]*routeCapture)}
+}
+
+// routeFor returns the route a request belongs to.
+func (c *captureRecorder) routeFor(method, path string) route {
+	if !c.rawPaths {
+		path = routeTemplate(path)
+	}
+	return route{method: method, path: path}
+}
+
+// record decodes body when it is JSON and adds its documents to the
+// requests of rt, or when status is not 0, to its responses with that
+// status, under the media type of header.
+func (c *captureRecorder) record(rt route, header http.Header, body []byte, status int) {
+	if len(body) == 0 {
+		return
+	}
+	if header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		if body, err = io.ReadAll(io.LimitReader(zr, maxCaptureBytes)); err != nil {
+			return
+		}
+	}
+	docs, err := decodeAll(newJSONDecoder(bytes.NewReader(body), nil))
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	capture := c.capture(rt)
+	mediaType := contentMediaType(header)
+	if status == 0 {
+		capture.requests[mediaType] = appendSamples(capture.requests[mediaType], docs)
+	} else {
+		key := responseKey{status, mediaType}
+		capture.responses[key] = appendSamples(capture.responses[key], docs)
+	}
+}
+
+// capture returns the capture of rt, creating it when needed. The caller
+// holds c.mu.
+func (c *captureRecorder) capture(rt route) *routeCapture {
+	capture, ok := c.routes[rt]
+	if !ok {
+		capture = &routeCapture{
+			requests:  make(map[string][]interface{}),
+			responses: make(map[responseKey][]interface{}),
+		}
+		c.routes[rt] = capture
+	}
+	return capture
+}
+
+// appendSamples adds docs to samples up to maxRouteSamples.
+func appendSamples(samples, docs []interface{}) []interface{} {
+	n := min(len(docs), max(maxRouteSamples-len(samples), 0))
+	return append(samples, docs[:n]...)
+}
+
+// sortedRoutes returns the recorded routes by path, then method. The caller
+// holds c.mu.
+func (c *captureRecorder) sortedRoutes() []route {
+	routes := make([]route, 0, len(c.routes))
+	for rt := range c.routes {
+		routes = append(routes, rt)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].path != routes[j].path {
+			return routes[i].path < routes[j].path
+		}
+		return routes[i].method < routes[j].method
+	})
+	return routes
+}
+
+// write renders the request and response shapes of every route, in route
+// order, under a heading for each route, direction and media type.
+func (c *captureRecorder) write(w io.Writer, render renderer, opts *renderOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	written := false
+	for _, rt := range c.sortedRoutes() {
+		capture := c.routes[rt]
+		for _, part := range []struct {
+			name   string
+			bodies map[string][]interface{}
+		}{{"request", capture.requests}, {"response", capture.responsesByType()}} {
+			for _, mediaType := range mediaTypes(part.bodies) {
+				docs := part.bodies[mediaType]
+				noun := "bodies"
+				if len(docs) == 1 {
+					noun = "body"
+				}
+				written = true
+				fmt.Fprintf(w, "== %s %s %s (%d %s) ==\n", rt, part.name, mediaType, len(docs), noun)
+				if err := render(w, analyzeJSON(mergeDocuments(docs)), opts); err != nil {
+					return err
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	if !written {
+		fmt.Fprintln(w, "no JSON bodies captured")
+	}
+	return nil
+}
+
+// newCaptureProxy returns a reverse proxy to target that records the JSON
+// request and response bodies passing through it.
+func newCaptureProxy(target *url.URL, rec *captureRecorder) http.Handler {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// Route by the path the client asked for, before it is joined
+			// onto the target's base path.
+			rt := rec.routeFor(pr.In.Method, pr.In.URL.Path)
+			rec.recordParams(rt, pr.In)
+			if pr.Out.Body != nil && pr.Out.Body != http.NoBody && isJSONContent(pr.Out.Header) {
+				header := pr.Out.Header
+				pr.Out.Body = captureBody(pr.Out.Body, func(body []byte) {
+					rec.record(rt, header, body, 0)
+				})
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out = pr.Out.WithContext(context.WithValue(pr.Out.Context(), routeKey{}, rt))
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if !isJSONContent(resp.Header) {
+				return nil
+			}
+			rt, _ := resp.Request.Context().Value(routeKey{}).(route)
+			resp.Body = captureBody(resp.Body, func(body []byte) {
+				rec.record(rt, resp.Header, body, resp.StatusCode)
+			})
+			return nil
+		},
+	}
+}
+
+type routeKey struct{}
+
+// contentMediaType returns the media type of a body's Content-Type header,
+// lowercased and without parameters such as charset.
+func contentMediaType(header http.Header) string {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, _, _ = strings.Cut(header.Get("Content-Type"), ";")
+		return strings.ToLower(strings.TrimSpace(mediaType))
+	}
+	return mediaType
+}
+
+// isJSONContent reports whether header declares a JSON body, including
+// types such as application/problem+json and application/x-ndjson. Other
+// bodies are forwarded without being buffered.
+func isJSONContent(header http.Header) bool {
+	return strings.Contains(header.Get("Content-Type"), "json")
+}
+
+// captureBody returns a replacement for body that forwards it as it is read,
+// keeping a copy of up to maxCaptureBytes that is passed to done once the
+// whole body has been read. Bodies over the limit, and bodies not read to
+// the end, are not recorded.
+func captureBody(body io.ReadCloser, done func([]byte)) io.ReadCloser {
+	return &capturedBody{ReadCloser: body, done: done}
+}
+
+type capturedBody struct {
+	io.ReadCloser
+	buf  []byte
+	over bool // more than maxCaptureBytes was read
+	done func([]byte)
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.over {
+		if len(b.buf)+n > maxCaptureBytes {
+			b.over, b.buf = true, nil
+		} else {
+			b.buf = append(b.buf, p[:n]...)
+		}
+	}
+	if err == io.EOF && b.done != nil {
+		if !b.over {
+			b.done(b.buf)
+		}
+		b.done = nil
+	}
+	return n, err
+}
+
+// runProxy implements "json-shape proxy --listen addr --target url". It
+// forwards traffic until interrupted, then prints the shapes it saw.
+func runProxy(args []string) error {
+	fs := flag.NewFlagSet("json-shape proxy", flag.ExitOnError)
+	listen := fs.String("listen", ":8888", "address to accept client connections on")
+	target := fs.String("target", "", "base URL of the API to forward requests to")
+	format := fs.String("format", "tree", "output format: "+formatNames()+"; openapi describes every route in one spec")
+	rawPaths := fs.Bool("raw-paths", false, "group bodies by concrete path instead of route template (/users/{id})")
+	auditErrors := fs.Bool("audit-errors", false, "report error responses that are not RFC 9457 problem details after the output")
+	fs.Parse(args)
+
+	render, ok := formats[*format]
+	if !ok {
+		return fmt.Errorf("unknown format %q (want one of: %s)", *format, formatNames())
+	}
+	targetURL, err := url.Parse(*target)
+	if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+		return fmt.Errorf("--target must be an absolute URL such as https://api.example.com")
+	}
+
+	rec := newCaptureRecorder()
+	rec.rawPaths = *rawPaths
+	server := &http.Server{Addr: *listen, Handler: newCaptureProxy(targetURL, rec)}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(os.Stderr, "proxying %s to %s; press Ctrl-C to print the shapes\n", *listen, targetURL)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	opts := &renderOptions{}
+	if *format == "openapi" {
+		err = rec.writeOpenAPI(os.Stdout, targetURL, opts)
+	} else {
+		err = rec.write(os.Stdout, render, opts)
+	}
+	if err != nil {
+		return err
+	}
+	for _, warning := range opts.warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", *format, warning)
+	}
+	if *auditErrors {
+		rec.writeProblemAudit(os.Stderr)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/users":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": 7, "echo": ` + string(body) + `}`))
+		case "/v1/users/7":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(`{"id": 7, "name": "Ada"}`))
+			zw.Close()
+		case "/v1/users/8":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type": "about:blank", "title": "Not Found", "status": 404}`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL + "/v1")
+	rec := newCaptureRecorder()
+	proxy := httptest.NewServer(newCaptureProxy(target, rec))
+	defer proxy.Close()
+
+	resp, err := http.Post(proxy.URL+"/users", "application/json", strings.NewReader(`{"name": "Ada"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"id": 7, "echo": {"name": "Ada"}}` {
+		t.Errorf("client received %q; the body must pass through unchanged", body)
+	}
+
+	// The client asks for gzip itself, so the proxy forwards it compressed.
+	req, _ := http.NewRequest("GET", proxy.URL+"/users/7", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	for _, path := range []string{"/users/8", "/health"} {
+		if resp, err = http.Get(proxy.URL + path); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// Bodies are recorded once the proxy has read them to the end.
+	proxy.Close()
+
+	var out bytes.Buffer
+	if err := rec.write(&out, formats["compact"], &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "== POST /users request application/json (1 body) ==\n" +
+		"name:string\n\n" +
+		"== POST /users response application/json (1 body) ==\n" +
+		"echo:object\n  name:string\nid:number\n\n" +
+		"== GET /users/{id} response application/json (1 body) ==\n" +
+		"id:number\nname:string\n\n" +
+		"== GET /users/{id} response application/problem+json (1 body) ==\n" +
+		"status:number\ntitle:string\ntype:string\n\n"
+	if out.String() != expected {
+		t.Errorf("write() =\n%s\nwant\n%s", out.String(), expected)
+	}
+}
+
+func TestCaptureProxyStreams(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": 1}`))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte(`, {"id": 2, "name": "Ada"}]`))
+	}))
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL)
+	rec := newCaptureRecorder()
+	proxy := httptest.NewServer(newCaptureProxy(target, rec))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make(chan string)
+	go func() {
+		buf := make([]byte, len(`[{"id": 1}`))
+		io.ReadFull(resp.Body, buf)
+		first <- string(buf)
+	}()
+	select {
+	case got := <-first:
+		if got != `[{"id": 1}` {
+			t.Errorf("client received %q first", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the start of the body did not reach the client before the backend finished it")
+	}
+	close(release)
+	rest, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(rest) != `, {"id": 2, "name": "Ada"}]` {
+		t.Errorf("client received %q last", rest)
+	}
+	proxy.Close()
+
+	var out bytes.Buffer
+	if err := rec.write(&out, formats["compact"], &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "== GET /users response application/json (1 body) ==\n" +
+		"id:number\nname:string?\n\n"
+	if out.String() != expected {
+		t.Errorf("write() =\n%s\nwant\n%s", out.String(), expected)
+	}
+}
+
+func TestCaptureRecorderRoutes(t *testing.T) {
+	rec := newCaptureRecorder()
+	header := http.Header{"Content-Type": {"application/json"}}
+	for _, path := range []string{"/users/1", "/users/2", "/users/3/orders"} {
+		rec.record(rec.routeFor("GET", path), header, []byte(`{"id": 1}`), http.StatusOK)
+	}
+	if len(rec.routes) != 2 || len(rec.routes[route{"GET", "/users/{id}"}].responses[responseKey{http.StatusOK, "application/json"}]) != 2 {
+		t.Errorf("expected concrete paths grouped by template, got %v", rec.routes)
+	}
+
+	rec = newCaptureRecorder()
+	rec.rawPaths = true
+	if rt := rec.routeFor("GET", "/users/1"); rt.path != "/users/1" {
+		t.Errorf("routeFor() with raw paths = %v", rt)
+	}
+}
+
+func TestContentMediaType(t *testing.T) {
+	tests := map[string]string{
+		"application/json":                "application/json",
+		"Application/JSON; charset=utf-8": "application/json",
+		"application/problem+json":        "application/problem+json",
+		"application/x-ndjson;bad":        "application/x-ndjson",
+	}
+	for contentType, want := range tests {
+		if got := contentMediaType(http.Header{"Content-Type": {contentType}}); got != want {
+			t.Errorf("contentMediaType(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// variableSegment matches path segments that identify a resource rather than
+// name a route: integers, UUIDs and hex identifiers such as MongoDB
+// ObjectIds or hashes.
+var variableSegment = regexp.MustCompile(`^(?:\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]*\d[0-9a-fA-F]*)$`)
+
+// minHexSegment is the shortest hex segment taken for an identifier, so that
+// words like "cafe" or "v2" stay literal.
+const minHexSegment = 8
+
+// routeTemplate normalizes a URL path into a route template by replacing
+// identifier segments with parameters: "/users/42/orders/17" becomes
+// "/users/{id}/orders/{id2}".
+func routeTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	params := 0
+	for i, segment := range segments {
+		if !isVariableSegment(segment) {
+			continue
+		}
+		params++
+		if params == 1 {
+			segments[i] = "{id}"
+		} else {
+			segments[i] = fmt.Sprintf("{id%d}", params)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isVariableSegment(segment string) bool {
+	if segment == "" || !variableSegment.MatchString(segment) {
+		return false
+	}
+	isDigits := strings.Trim(segment, "0123456789") == ""
+	return isDigits || strings.Contains(segment, "-") || len(segment) >= minHexSegment
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestRouteTemplate(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/", "/"},
+		{"/users", "/users"},
+		{"/users/42", "/users/{id}"},
+		{"/users/42/orders", "/users/{id}/orders"},
+		{"/users/42/orders/17", "/users/{id}/orders/{id2}"},
+		{"/items/3fa85f64-5717-4562-b3fc-2c963f66afa6", "/items/{id}"},
+		{"/docs/507f1f77bcf86cd799439011/", "/docs/{id}/"},
+		{"/v2/cafe/beef", "/v2/cafe/beef"},
+		{"/blobs/deadbeefcafe", "/blobs/deadbeefcafe"},
+		{"/commits/9fceb02d0ae598e95dc970b74767f19372d61af8", "/commits/{id}"},
+	}
+
+	for _, tt := range tests {
+		if result := routeTemplate(tt.path); result != tt.expected {
+			t.Errorf("routeTemplate(%q) = %q; want %q", tt.path, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var errZipMissing = errors.New("missing archive member")
+
+// sheetDecoder reads one sheet of a zipped spreadsheet and returns its rows
+// as a single document: an array of objects keyed by the header row, in
+// which empty cells are left out.
+type sheetDecoder struct {
+	r     io.Reader
+	sheet string
+	read  func(zr *zip.Reader, sheet string, size *sheetSize) ([][]interface{}, error)
+	order *keyOrder
+	limit int64 // on the bytes of XML to unzip; 0 for none
+	docs  int   // on the rows below the header; 0 for none
+	done  bool
+}
+
+func newXLSXDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return newSheetDecoder(r, opts, readXLSX)
+}
+
+func newODSDecoder(r io.Reader, opts *decodeOptions) valueDecoder {
+	return newSheetDecoder(r, opts, readODS)
+}
+
+func newSheetDecoder(r io.Reader, opts *decodeOptions, read func(*zip.Reader, string, *sheetSize) ([][]interface{}, error)) valueDecoder {
+	d := &sheetDecoder{r: r, read: read}
+	if opts != nil {
+		d.sheet, d.order, d.limit, d.docs = opts.sheet, opts.keyOrder, opts.maxInputBytes, opts.maxDocs
+	}
+	return d
+}
+
+func (d *sheetDecoder) Decode() (interface{}, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+	d.done = true
+
+	// Zip archives need random access, so the file is read into memory.
+	data, err := io.ReadAll(d.r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet: %v", err)
+	}
+	if err := d.checkSize(zr); err != nil {
+		return nil, err
+	}
+	rows, err := d.read(zr, d.sheet, &sheetSize{maxBytes: d.limit, maxRows: d.docs})
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet: %w", err)
+	}
+	return rowsToObjects(rows, d.order), nil
+}
+
+// checkSize holds the XML members of zr, which are all that is read, to
+// d.limit: they can unzip to far more than the archive takes. archive/zip
+// fails reading past the size a member declares, so the declared sizes are
+// what is checked.
+func (d *sheetDecoder) checkSize(zr *zip.Reader) error {
+	if d.limit <= 0 {
+		return nil
+	}
+	var size uint64
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".xml") || strings.HasSuffix(f.Name, ".rels") {
+			size += f.UncompressedSize64
+		}
+	}
+	if size > uint64(d.limit) {
+		return &limitError{flag: "max-input-bytes", limit: d.limit, what: "unzipped spreadsheet is larger than"}
+	}
+	return nil
+}
+
+// sheetSize counts the rows a sheet expands into and the bytes of their
+// cell values, so that --max-docs and --max-input-bytes fail the read while
+// rows are expanded: ODS repeat counts let a few bytes of XML stand for
+// millions of cells, which checkSize cannot see.
+type sheetSize struct {
+	maxBytes int64 // 0 for no limit
+	maxRows  int   // 0 for no limit
+	bytes    int64
+	rows     int
+	header   bool // the header row, which is no document, was seen
+}
+
+// add counts n copies of row, each cell value as its text and a separator.
+func (s *sheetSize) add(row []interface{}, n int) error {
+	if isEmptyRow(row) {
+		return nil
+	}
+	if !s.header {
+		s.header = true
+		n--
+	}
+	s.rows += n
+	if s.maxRows > 0 && s.rows > s.maxRows {
+		return &limitError{flag: "max-docs", limit: int64(s.maxRows), what: "input has more documents than"}
+	}
+	var size int64
+	for _, cell := range row {
+		if cell != nil {
+			size += int64(len(fmt.Sprint(cell))) + 1
+		}
+	}
+	s.bytes += size * int64(n)
+	if s.maxBytes > 0 && s.bytes > s.maxBytes {
+		return &limitError{flag: "max-input-bytes", limit: s.maxBytes, what: "expanded spreadsheet is larger than"}
+	}
+	return nil
+}
+
+// selectSheet returns the index in names of the sheet named by sheet, which
+// may also be a 1-based position. An empty sheet selects the first one.
+func selectSheet(names []string, sheet string) (int, error) {
+	if len(names) == 0 {
+		return 0, fmt.Errorf("no sheets found")
+	}
+	if sheet == "" {
+		return 0, nil
+	}
+	for i, name := range names {
+		if name == sheet {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(sheet); err == nil && n >= 1 && n <= len(names) {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("sheet %q not found (sheets: %s)", sheet, strings.Join(names, ", "))
+}
+
+// rowsToObjects turns the first non-empty row into column names and every
+// later row into an object. Blank headers are named after their column
+// ("column3") and repeated headers get a numeric suffix ("name_2"). The
+// column order is recorded in order, which may be nil.
+func rowsToObjects(rows [][]interface{}, order *keyOrder) []interface{} {
+	objects := []interface{}{}
+	var header []string
+	for _, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		if header == nil {
+			header = headerNames(row)
+			continue
+		}
+
+		obj := make(map[string]interface{})
+		for i, cell := range row {
+			if cell == nil {
+				continue
+			}
+			for i >= len(header) {
+				header = append(header, fmt.Sprintf("column%d", len(header)+1))
+			}
+			obj[header[i]] = cell
+		}
+		objects = append(objects, obj)
+	}
+	order.add("", header...)
+	return objects
+}
+
+func headerNames(row []interface{}) []string {
+	names := make([]string, len(row))
+	seen := make(map[string]int)
+	for i, cell := range row {
+		name := strings.TrimSpace(fmt.Sprint(cell))
+		if cell == nil || name == "" {
+			name = fmt.Sprintf("column%d", i+1)
+		}
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+		names[i] = name
+	}
+	return names
+}
+
+func isEmptyRow(row []interface{}) bool {
+	for _, cell := range row {
+		if cell != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// maxColumns bounds the columns of a row, as Excel does, so that a cell
+// reference or repeat count cannot make a row of any length.
+const maxColumns = 1 << 14
+
+// setCell stores value at column col of row, growing row as needed.
+func setCell(row []interface{}, col int, value interface{}) ([]interface{}, error) {
+	if col < 0 || col >= maxColumns {
+		return nil, fmt.Errorf("cell in column %d, beyond the limit of %d columns", col+1, maxColumns)
+	}
+	for len(row) <= col {
+		row = append(row, nil)
+	}
+	row[col] = value
+	return row, nil
+}
+
+func openZipFile(zr *zip.Reader, name string) (io.ReadCloser, error) {
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", name, errZipMissing)
+}
+
+// decodeZipXML unmarshals the XML archive member name into v. It returns
+// an error wrapping errZipMissing when the member does not exist.
+func decodeZipXML(zr *zip.Reader, name string, v interface{}) error {
+	f, err := openZipFile(zr, name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
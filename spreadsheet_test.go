@@ -0,0 +1,90 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// zipArchive builds an in-memory zip file from member names and contents.
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRowsToObjects(t *testing.T) {
+	rows := [][]interface{}{
+		nil,
+		{"id", "name", nil, "name"},
+		{1.0, "Ada", "x", "Lovelace"},
+		{},
+		{2.0, nil, nil, nil, true},
+	}
+	expected := []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "Ada", "column3": "x", "name_2": "Lovelace"},
+		map[string]interface{}{"id": 2.0, "column5": true},
+	}
+
+	result := rowsToObjects(rows, nil)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("rowsToObjects() = %#v; want %#v", result, expected)
+	}
+}
+
+func TestSelectSheet(t *testing.T) {
+	names := []string{"Summary", "2", "Data"}
+	tests := []struct {
+		sheet    string
+		expected int
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"Data", 2, false},
+		{"2", 1, false}, // a sheet named "2" wins over the position
+		{"3", 2, false},
+		{"4", 0, true},
+		{"Missing", 0, true},
+	}
+
+	for _, tt := range tests {
+		result, err := selectSheet(names, tt.sheet)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("selectSheet(%q) error = %v; wantErr %v", tt.sheet, err, tt.wantErr)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("selectSheet(%q) = %d; want %d", tt.sheet, result, tt.expected)
+		}
+	}
+}
+
+func TestInputForName(t *testing.T) {
+	tests := map[string]string{
+		"data.json":   "json",
+		"stdin":       "json",
+		"events.MPK":  "msgpack",
+		"dump.bson":   "bson",
+		"report.xlsx": "xlsx",
+		"users.CSV":   "csv",
+		"genes.tab":   "tsv",
+		"https://acct.blob.core.windows.net/c/sheet.ods?sv=2022&sig=x": "ods",
+	}
+	for name, expected := range tests {
+		if result := inputForName(name); result != expected {
+			t.Errorf("inputForName(%q) = %q; want %q", name, result, expected)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxEnumValues limits enum detection: a string field is an enum when it
+// takes at most maxEnumValues distinct values, each no longer than
+// maxExactValueLen bytes, and every value is seen at least twice on average.
+const maxEnumValues = 10
+
+// fieldStats summarizes the values seen for a field: the range and mean of
+// numbers, the range of string lengths, number of distinct strings and the
+// formats they share, how often booleans are true, and the range of array
+// lengths.
+type fieldStats struct {
+	numbers   int
+	min, max  float64
+	sum       float64
+	fractions bool // some number is not an integer
+
+	strings        int
+	minLen, maxLen int
+	misfits        uint // stringFormats some string does not match
+
+	distinct distinctValues
+
+	booleans, trues int
+
+	arrays             int
+	minItems, maxItems int
+}
+
+func (s *fieldStats) observe(value interface{}) {
+	switch v := value.(type) {
+	case float64:
+		if s.numbers == 0 || v < s.min {
+			s.min = v
+		}
+		if s.numbers == 0 || v > s.max {
+			s.max = v
+		}
+		s.numbers++
+		s.sum += v
+		if v != math.Trunc(v) {
+			s.fractions = true
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.strings == 0 || n < s.minLen {
+			s.minLen = n
+		}
+		if s.strings == 0 || n > s.maxLen {
+			s.maxLen = n
+		}
+		s.strings++
+		s.misfits = formatMisfits(v, s.misfits)
+		s.distinct.add(v, 1)
+	case bool:
+		s.booleans++
+		if v {
+			s.trues++
+		}
+	case []interface{}:
+		n := len(v)
+		if s.arrays == 0 || n < s.minItems {
+			s.minItems = n
+		}
+		if s.arrays == 0 || n > s.maxItems {
+			s.maxItems = n
+		}
+		s.arrays++
+	}
+}
+
+func (s *fieldStats) merge(o *fieldStats) {
+	if o.numbers > 0 {
+		if s.numbers == 0 || o.min < s.min {
+			s.min = o.min
+		}
+		if s.numbers == 0 || o.max > s.max {
+			s.max = o.max
+		}
+		s.numbers += o.numbers
+		s.sum += o.sum
+		s.fractions = s.fractions || o.fractions
+	}
+	if o.strings > 0 {
+		if s.strings == 0 || o.minLen < s.minLen {
+			s.minLen = o.minLen
+		}
+		if s.strings == 0 || o.maxLen > s.maxLen {
+			s.maxLen = o.maxLen
+		}
+		s.strings += o.strings
+		s.misfits |= o.misfits
+		s.distinct.merge(&o.distinct)
+	}
+	s.booleans += o.booleans
+	s.trues += o.trues
+	if o.arrays > 0 {
+		if s.arrays == 0 || o.minItems < s.minItems {
+			s.minItems = o.minItems
+		}
+		if s.arrays == 0 || o.maxItems > s.maxItems {
+			s.maxItems = o.maxItems
+		}
+		s.arrays += o.arrays
+	}
+}
+
+// enumValues returns the sorted values of a string field that looks like an
+// enum, or nil.
+func (s *fieldStats) enumValues() []string {
+	counts := s.distinct.counts()
+	if len(counts) == 0 || len(counts) > maxEnumValues || s.strings < 2*len(counts) {
+		return nil
+	}
+	values := make([]string, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func (s *fieldStats) mean() float64 {
+	return s.sum / float64(s.numbers)
+}
+
+// String renders the statistics for the tree output, e.g.
+// "[integer, min 1, max 30, mean 12.5]" or "[date, length 10-10, distinct
+// 3]", or "" if no values were observed.
+func (s *fieldStats) String() string {
+	var parts []string
+	if s.numbers > 0 {
+		kind := "integer"
+		if s.fractions {
+			kind = "float"
+		}
+		parts = append(parts, kind, "min "+formatNumber(s.min), "max "+formatNumber(s.max), "mean "+formatNumber(s.mean()))
+	}
+	if s.strings > 0 {
+		for i, f := range stringFormats {
+			if dateFormats&(1<<i) != 0 && s.misfits&(1<<i) == 0 {
+				parts = append(parts, f.id)
+				break
+			}
+		}
+		parts = append(parts, fmt.Sprintf("length %d-%d", s.minLen, s.maxLen))
+		if n, exact := s.distinct.count(); exact {
+			parts = append(parts, fmt.Sprintf("distinct %d", n))
+		} else {
+			parts = append(parts, fmt.Sprintf("distinct ~%d", n))
+		}
+	}
+	if s.arrays > 0 {
+		parts = append(parts, fmt.Sprintf("items %d-%d", s.minItems, s.maxItems))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFieldStats(t *testing.T) {
+	var s fieldStats
+	for _, v := range []interface{}{3.0, -1.0, 10.0, "héllo", "", []interface{}{1.0}, []interface{}{}, nil, true} {
+		s.observe(v)
+	}
+
+	if s.numbers != 3 || s.min != -1 || s.max != 10 || s.mean() != 4 {
+		t.Errorf("unexpected number stats: %+v", s)
+	}
+	if s.strings != 2 || s.minLen != 0 || s.maxLen != 5 {
+		t.Errorf("unexpected string stats: %+v", s)
+	}
+	if s.arrays != 2 || s.minItems != 0 || s.maxItems != 1 {
+		t.Errorf("unexpected array stats: %+v", s)
+	}
+	if got := s.String(); got != "[integer, min -1, max 10, mean 4, length 0-5, distinct 2, items 0-1]" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestFieldStatsMerge(t *testing.T) {
+	var a, b fieldStats
+	a.observe(5.0)
+	b.observe(1.0)
+	b.observe(9.0)
+	b.observe("abc")
+	a.merge(&b)
+
+	if a.numbers != 3 || a.min != 1 || a.max != 9 || a.mean() != 5 {
+		t.Errorf("unexpected merged number stats: %+v", a)
+	}
+	if a.strings != 1 || a.minLen != 3 || a.maxLen != 3 {
+		t.Errorf("unexpected merged string stats: %+v", a)
+	}
+
+	var empty fieldStats
+	if empty.String() != "" {
+		t.Errorf("expected no output without observations, got %q", empty.String())
+	}
+}
+
+func TestAnalyzeJSONStats(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{"user": map[string]interface{}{"age": 20.0}},
+		map[string]interface{}{"user": map[string]interface{}{"age": 40.0}},
+	}
+
+	fields := analyzeJSON(data)
+	age := fields["user"].Children["age"]
+	if age.stats.numbers != 2 || age.stats.min != 20 || age.stats.max != 40 {
+		t.Errorf("stats should be merged across objects: %+v", age.stats)
+	}
+
+	var buf bytes.Buffer
+	writeTree(&buf, fields, "", true, &renderOptions{stats: true})
+	if !strings.Contains(buf.String(), "age: number [integer, min 20, max 40, mean 30]") {
+		t.Errorf("tree output missing stats:\n%s", buf.String())
+	}
+}
+
+func TestFieldStatsEnumValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{"repeated", []string{"b", "a", "b", "a", "a"}, []string{"a", "b"}},
+		{"mostly unique", []string{"a", "b", "c", "a"}, nil},
+		{"too many", []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}, nil},
+		{"too long", []string{strings.Repeat("x", maxExactValueLen+1), strings.Repeat("x", maxExactValueLen+1)}, nil},
+	}
+	for _, tt := range tests {
+		var half, rest fieldStats
+		for i, v := range tt.values {
+			if i%2 == 0 {
+				half.observe(v)
+			} else {
+				rest.observe(v)
+			}
+		}
+		half.merge(&rest)
+		if got := half.enumValues(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: enumValues() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// locatedViolation is a violation with the byte offset in its input of the
+// offending value, or of the object missing a field, or -1 when the input
+// was decoded rather than streamed and offsets are not known.
+type locatedViolation struct {
+	violation
+	offset int64
+}
+
+// streamChecker validates JSON documents token by token as they are read,
+// so that memory stays constant however large the input and each document
+// are: it holds the schema, the path to the current value and, for each
+// open object, which required fields it has held.
+type streamChecker struct {
+	v   *schemaValidator
+	dec *json.Decoder
+	out []locatedViolation // of the current document
+}
+
+// validateStream validates each JSON document read from r, calling visit
+// with the offset where it starts and its violations. The elements of a
+// top-level array are documents, as when documents are decoded.
+//
+// Violations come in document order, with missing fields and item counts
+// reported at the end of their object or array.
+func (v *schemaValidator) validateStream(r io.Reader, visit func(offset int64, violations []locatedViolation) error) error {
+	c := &streamChecker{v: v, dec: json.NewDecoder(r)}
+	for {
+		start := c.start()
+		tok, err := c.dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return c.syntaxError(err)
+		}
+		if tok != json.Delim('[') {
+			if err := c.document(tok, start, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		for c.dec.More() {
+			start := c.start()
+			tok, err := c.dec.Token()
+			if err != nil {
+				return c.syntaxError(err)
+			}
+			if err := c.document(tok, start, visit); err != nil {
+				return err
+			}
+		}
+		if _, err := c.dec.Token(); err != nil {
+			return c.syntaxError(err)
+		}
+	}
+}
+
+// document validates the document starting with tok.
+func (c *streamChecker) document(tok json.Token, start int64, visit func(int64, []locatedViolation) error) error {
+	c.out = c.out[:0]
+	if err := c.value(c.v.root, tok, start, ""); err != nil {
+		return err
+	}
+	return visit(start, c.out)
+}
+
+// value checks the value starting with tok, at offset start, against s.
+// Values that no schema describes are skipped.
+func (c *streamChecker) value(s *jsonSchema, tok json.Token, start int64, path string) error {
+	if s == nil {
+		return c.skip(tok)
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		c.check(start, func(out *[]violation) { c.v.check(s, tok, path, out) })
+		return nil
+	}
+	if len(s.Enum) > 0 {
+		// Comparing with enum values takes the whole value.
+		value, err := c.decodeRest(delim)
+		if err != nil {
+			return err
+		}
+		c.check(start, func(out *[]violation) { c.v.check(s, value, path, out) })
+		return nil
+	}
+
+	// An empty value of the right kind stands in for the value itself, for
+	// the type check.
+	var kind interface{} = map[string]interface{}{}
+	if delim == '[' {
+		kind = []interface{}{}
+	}
+	matched := true
+	c.check(start, func(out *[]violation) { matched = c.v.checkValue(s, kind, path, out) })
+	switch {
+	case !matched:
+		return c.skip(tok)
+	case delim == '{':
+		return c.object(s, start, path)
+	default:
+		return c.array(s, start, path)
+	}
+}
+
+// object checks the members of an object whose "{" was read.
+func (c *streamChecker) object(s *jsonSchema, start int64, path string) error {
+	held := make([]bool, len(s.Required))
+	for c.dec.More() {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return c.syntaxError(err)
+		}
+		key := tok.(string)
+		if i := slices.Index(s.Required, key); i >= 0 {
+			held[i] = true
+		}
+		memberStart := c.start()
+		if tok, err = c.dec.Token(); err != nil {
+			return c.syntaxError(err)
+		}
+		if err := c.value(s.Properties.schemas[key], tok, memberStart, fieldPath(path, key)); err != nil {
+			return err
+		}
+	}
+	if _, err := c.dec.Token(); err != nil {
+		return c.syntaxError(err)
+	}
+	for i, key := range s.Required {
+		if !held[i] {
+			c.out = append(c.out, locatedViolation{violation{fieldPath(path, key), "missing", "missing"}, start})
+		}
+	}
+	return nil
+}
+
+// array checks the elements of an array whose "[" was read.
+func (c *streamChecker) array(s *jsonSchema, start int64, path string) error {
+	n := 0
+	for ; c.dec.More(); n++ {
+		itemStart := c.start()
+		tok, err := c.dec.Token()
+		if err != nil {
+			return c.syntaxError(err)
+		}
+		if err := c.value(s.Items, tok, itemStart, fmt.Sprintf("%s[%d]", path, n)); err != nil {
+			return err
+		}
+	}
+	if _, err := c.dec.Token(); err != nil {
+		return c.syntaxError(err)
+	}
+	if s.MinItems != nil && n < *s.MinItems {
+		c.out = append(c.out, locatedViolation{violation{path, "range", fmt.Sprintf("%d items, less than minItems %d", n, *s.MinItems)}, start})
+	}
+	if s.MaxItems != nil && n > *s.MaxItems {
+		c.out = append(c.out, locatedViolation{violation{path, "range", fmt.Sprintf("%d items, more than maxItems %d", n, *s.MaxItems)}, start})
+	}
+	return nil
+}
+
+// check runs a check of the schemaValidator, locating the violations it
+// finds at start.
+func (c *streamChecker) check(start int64, check func(out *[]violation)) {
+	var found []violation
+	check(&found)
+	for _, v := range found {
+		c.out = append(c.out, locatedViolation{v, start})
+	}
+}
+
+// skip reads past the rest of the value starting with tok.
+func (c *streamChecker) skip(tok json.Token) error {
+	if _, ok := tok.(json.Delim); !ok {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return c.syntaxError(err)
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// decodeRest decodes the rest of the object or array opened by delim.
+func (c *streamChecker) decodeRest(delim json.Delim) (interface{}, error) {
+	var value interface{}
+	if delim == '{' {
+		members := make(map[string]interface{})
+		for c.dec.More() {
+			key, err := c.dec.Token()
+			if err != nil {
+				return nil, c.syntaxError(err)
+			}
+			var member interface{}
+			if err := c.dec.Decode(&member); err != nil {
+				return nil, c.syntaxError(err)
+			}
+			members[key.(string)] = member
+		}
+		value = members
+	} else {
+		items := []interface{}{}
+		for c.dec.More() {
+			var item interface{}
+			if err := c.dec.Decode(&item); err != nil {
+				return nil, c.syntaxError(err)
+			}
+			items = append(items, item)
+		}
+		value = items
+	}
+	if _, err := c.dec.Token(); err != nil {
+		return nil, c.syntaxError(err)
+	}
+	return value, nil
+}
+
+// start returns the offset of the next value: the decoder's offset is the
+// end of the last token, so the whitespace, commas and colons after it are
+// skipped as far as they are buffered.
+func (c *streamChecker) start() int64 {
+	offset := c.dec.InputOffset()
+	buffered, ok := c.dec.Buffered().(io.ByteReader)
+	if !ok {
+		return offset
+	}
+	for {
+		b, err := buffered.ReadByte()
+		if err != nil {
+			return offset
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+}
+
+func (c *streamChecker) syntaxError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("byte %d: %v", errorOffset(err, c.dec.InputOffset()), err)
+}
+
+// validateSources validates the documents of every source as they are
+// read, calling report with the number of each document, counted across
+// sources, and its violations. JSON is streamed with validateStream;
+// other encodings, and JSON read with --skip-invalid or --transform, are
+// decoded one document at a time, without offsets. It returns the number
+// of documents.
+//
+// The limits and --skip-invalid apply as in readSources.
+func validateSources(sources []source, input string, opts *decodeOptions, v *schemaValidator, log io.Writer, report func(doc int, src string, violations []locatedViolation)) (int, error) {
+	bytesLeft := &inputBudget{limit: opts.maxInputBytes}
+	docsLeft := &docBudget{limit: opts.maxDocs}
+	var skippedRecords, skippedSources int
+	for _, src := range sources {
+		rc, err := src.open()
+		if err != nil {
+			return docsLeft.used, err
+		}
+		var reader io.Reader = rc
+		if bytesLeft.limit > 0 {
+			reader = &budgetReader{r: rc, b: bytesLeft}
+		}
+		encoding := input
+		if encoding == "" {
+			encoding = inputForName(src.name)
+		}
+
+		if encoding == "json" && !opts.skipInvalid && opts.transform == nil {
+			err = v.validateStream(reader, func(offset int64, violations []locatedViolation) error {
+				if err := docsLeft.take(1); err != nil {
+					return err
+				}
+				report(docsLeft.used, src.name, violations)
+				return nil
+			})
+		} else {
+			err = validateDecoded(decoders[encoding](reader, opts), opts.transform, v, docsLeft, func(err error) {
+				skippedRecords++
+				fmt.Fprintf(log, "warning: %s: skipping invalid record: %v\n", src.name, err)
+			}, func(violations []locatedViolation) {
+				report(docsLeft.used, src.name, violations)
+			})
+		}
+		rc.Close()
+		var lerr *limitError
+		switch {
+		case bytesLeft.exceeded:
+			return docsLeft.used, fmt.Errorf("%s: %w", src.name, bytesLeft.err())
+		case docsLeft.exceeded, errors.As(err, &lerr):
+			return docsLeft.used, fmt.Errorf("%s: %w", src.name, err)
+		}
+		if err != nil && opts.skipInvalid && len(sources) > 1 {
+			skippedSources++
+			fmt.Fprintf(log, "warning: %s: skipping input: %v\n", src.name, err)
+			continue
+		}
+		if err != nil {
+			return docsLeft.used, fmt.Errorf("parsing %s: %v", src.name, err)
+		}
+	}
+
+	if skippedRecords > 0 || skippedSources > 0 {
+		fmt.Fprintf(log, "skipped %d invalid records and %d inputs\n", skippedRecords, skippedSources)
+	}
+	return docsLeft.used, nil
+}
+
+// validateDecoded validates the documents of dec one at a time. With
+// opts.skipInvalid, decoders report the records they can continue past to
+// onInvalid.
+func validateDecoded(dec valueDecoder, transform *docTransform, v *schemaValidator, docs *docBudget, onInvalid func(error), report func([]locatedViolation)) error {
+	for {
+		doc, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		var derr *decodeError
+		if errors.As(err, &derr) && derr.recoverable {
+			onInvalid(err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("document %d: %w", docs.used+1, err)
+		}
+		items, err := transform.apply([]interface{}{doc})
+		if err != nil {
+			return fmt.Errorf("document %d: %v", docs.used+1, err)
+		}
+		for _, item := range items {
+			if err := docs.take(1); err != nil {
+				return err
+			}
+			var located []locatedViolation
+			for _, violation := range v.validate(item) {
+				located = append(located, locatedViolation{violation, -1})
+			}
+			report(located)
+		}
+	}
+}
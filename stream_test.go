@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // "document: offset path\tmessage"
+	}{
+		{"valid", `{"id": 1, "status": "open", "note": null, "tags": [{"k": "a"}]}`, nil},
+		{"missing", `{"note": "x"}`, []string{"1: 0 id\tmissing", "1: 0 status\tmissing"}},
+		{"wrong types", `{"id": 1.5, "status": 3}`, []string{"1: 7 id\twant integer, got number", "1: 22 status\twant string, got number"}},
+		{"wrong type skips contents", `{"id": 1, "status": "open", "legacy": [{"code": "x"}]}`, []string{"1: 38 legacy\twant object, got array"}},
+		{"enum", `{"id": 1, "status": "gone"}`, []string{"1: 20 status\t" + `"gone" is not one of the enum values`}},
+		{"constraints", `{"id": 0, "status": "open", "note": "toolong", "tags": [{"k": "a"}, {"k": "b"}, {}]}`, []string{
+			"1: 7 id\t0 is less than the minimum 1",
+			"1: 36 note\tlength 7 is more than maxLength 5",
+			"1: 80 tags[2].k\tmissing",
+			"1: 55 tags\t3 items, more than maxItems 2",
+		}},
+		{"unknown members skipped", `{"id": 1, "status": "open", "extra": {"a": [1, {"b": 2}]}}`, nil},
+		{"several documents", "{\"id\": 1, \"status\": \"open\"}\n{\"id\": -1, \"status\": \"open\"}\n", []string{"2: 35 id\t-1 is less than the minimum 1"}},
+		{"top-level array", `[{"id": 1, "status": "open"}, {"status": "open"}]`, []string{"2: 30 id\tmissing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			docs := 0
+			err := newSchemaValidator(parseTestSchema(t)).validateStream(strings.NewReader(tt.input), func(offset int64, violations []locatedViolation) error {
+				docs++
+				for _, v := range violations {
+					got = append(got, fmt.Sprintf("%d: %d %s", docs, v.offset, v.violation))
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateStream() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateStreamMatchesDecoded checks that streaming finds the same
+// violations as validating decoded documents, if in another order.
+func TestValidateStreamMatchesDecoded(t *testing.T) {
+	input := `{"id": 0, "status": "gone", "note": "toolong", "tags": [{"k": 1}, {}, {"k": "c"}], "legacy": {"code": "x"}}
+{"status": ["open"], "legacy": null}`
+
+	var streamed []string
+	err := newSchemaValidator(parseTestSchema(t)).validateStream(strings.NewReader(input), func(_ int64, violations []locatedViolation) error {
+		for _, v := range violations {
+			streamed = append(streamed, v.violation.String())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []string
+	v := newSchemaValidator(parseTestSchema(t))
+	dec := json.NewDecoder(strings.NewReader(input))
+	for dec.More() {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		for _, violation := range v.validate(doc) {
+			decoded = append(decoded, violation.String())
+		}
+	}
+
+	slices.Sort(streamed)
+	slices.Sort(decoded)
+	if !reflect.DeepEqual(streamed, decoded) {
+		t.Errorf("streamed %q\ndecoded  %q", streamed, decoded)
+	}
+}
+
+func TestValidateStreamSyntaxError(t *testing.T) {
+	docs := 0
+	err := newSchemaValidator(parseTestSchema(t)).validateStream(strings.NewReader(`{"id": 1, "status": "open"} {"id": }`), func(int64, []locatedViolation) error {
+		docs++
+		return nil
+	})
+	if docs != 1 || err == nil || !strings.HasPrefix(err.Error(), "byte 35: ") {
+		t.Errorf("validateStream() validated %d documents, error %v", docs, err)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// docTransform preprocesses each document before it is analyzed, as set
+// with --transform: a list of steps, separated by semicolons or commas, run
+// in order on every document.
+//
+//	unwrap data               the value at data is the document; an array
+//	                          gives a document per element, and documents
+//	                          without it are dropped
+//	rename user.mail email    rename a key, keeping its value; renaming onto
+//	                          a key the object holds, or several keys
+//	                          matching a glob onto one, is an error
+//	drop debug, drop items[].*_raw
+//	                          delete the values at a path
+//	where type == "order"     keep the documents where a value at the path
+//	where deleted missing     equals (==) or differs from (!=) a JSON value
+//	                          or bare word, exists or is missing
+//
+// Paths are field paths, in which bare keys may hold "*" and "?" wildcards
+// and "[]" stands for every element of an array. A separator inside a key
+// or value must be quoted: `drop ["a,b"]`, `where note == "x; y"`.
+type docTransform struct {
+	steps []transformStep
+	moves []keyMove // of each step, nil for steps that move no keys
+}
+
+// transformStep turns a document into the documents that replace it: none,
+// one or, for unwrap, several.
+type transformStep func(doc interface{}) ([]interface{}, error)
+
+// keyMove returns where a step moves the value at the path of keys, for
+// keeping the key order of --sort=original through unwrap and rename, or
+// false when the value is no longer in the documents.
+type keyMove func(keys []string) ([]string, bool)
+
+// transformOps are the step names with the number of arguments they take,
+// where taking 2 or 3.
+var transformOps = map[string]int{"unwrap": 1, "rename": 2, "drop": 1, "where": 2}
+
+// parseTransform parses the steps of s. An empty s is no transform.
+func parseTransform(s string) (*docTransform, error) {
+	t := &docTransform{}
+	for _, text := range splitOutsideQuotes(s, ",;") {
+		words := splitOutsideQuotes(text, " \t")
+		if len(words) == 0 {
+			continue
+		}
+		step, move, err := parseTransformStep(words)
+		if err != nil {
+			return nil, fmt.Errorf("--transform: %s: %v", strings.TrimSpace(text), err)
+		}
+		t.steps = append(t.steps, step)
+		t.moves = append(t.moves, move)
+	}
+	if len(t.steps) == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
+
+func parseTransformStep(words []string) (transformStep, keyMove, error) {
+	op, args := words[0], words[1:]
+	want, ok := transformOps[op]
+	switch {
+	case !ok:
+		return nil, nil, fmt.Errorf("unknown step %q (want one of: unwrap, rename, drop, where)", op)
+	case op == "where" && (len(args) == 2 || len(args) == 3):
+	case op == "where":
+		return nil, nil, errWhereCondition
+	case len(args) != want:
+		return nil, nil, fmt.Errorf("%s takes %d arguments, got %d", op, want, len(args))
+	}
+	segments, err := parsePath(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch op {
+	case "unwrap":
+		unwrap := func(doc interface{}) ([]interface{}, error) {
+			var docs []interface{}
+			for _, value := range valuesAt(doc, segments) {
+				if items, ok := value.([]interface{}); ok {
+					docs = append(docs, items...)
+				} else {
+					docs = append(docs, value)
+				}
+			}
+			return docs, nil
+		}
+		// Only the keys within the unwrapped values are left, moved up.
+		move := func(keys []string) ([]string, bool) {
+			n, ok := matchKeyPath(segments, keys)
+			if !ok || n == len(keys) {
+				return nil, false
+			}
+			return keys[n:], true
+		}
+		return unwrap, move, nil
+	case "rename":
+		last := len(segments) - 1
+		if segments[last].element {
+			return nil, nil, fmt.Errorf("%s does not end with a key", args[0])
+		}
+		key, err := parseKey(args[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		rename := func(doc interface{}) ([]interface{}, error) {
+			for _, parent := range valuesAt(doc, segments[:last]) {
+				object, ok := parent.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				olds := matchingKeys(object, segments[last])
+				switch {
+				case len(olds) == 0 || len(olds) == 1 && olds[0] == key:
+					continue
+				case len(olds) > 1:
+					sort.Strings(olds)
+					return nil, fmt.Errorf("rename %s %s: several keys match (%s)", args[0], args[1], strings.Join(olds, ", "))
+				}
+				if _, taken := object[key]; taken {
+					return nil, fmt.Errorf("rename %s %s: the object already holds %s", args[0], args[1], args[1])
+				}
+				object[key] = object[olds[0]]
+				delete(object, olds[0])
+			}
+			return []interface{}{doc}, nil
+		}
+		// The renamed key takes the position of the old one.
+		move := func(keys []string) ([]string, bool) {
+			if n, ok := matchKeyPath(segments, keys); ok {
+				keys = append([]string(nil), keys...)
+				keys[n-1] = key
+			}
+			return keys, true
+		}
+		return rename, move, nil
+	case "drop":
+		last := len(segments) - 1
+		if segments[last].element {
+			return nil, nil, fmt.Errorf("%s does not end with a key", args[0])
+		}
+		return func(doc interface{}) ([]interface{}, error) {
+			for _, parent := range valuesAt(doc, segments[:last]) {
+				if object, ok := parent.(map[string]interface{}); ok {
+					for _, key := range matchingKeys(object, segments[last]) {
+						delete(object, key)
+					}
+				}
+			}
+			return []interface{}{doc}, nil
+		}, nil, nil
+	}
+
+	step, err := parseWhere(segments, args[1:])
+	return step, nil, err
+}
+
+var errWhereCondition = errors.New("where takes a path and a condition: == value, != value, exists or missing")
+
+// parseWhere parses the condition of a where step.
+func parseWhere(segments []pathSegment, args []string) (transformStep, error) {
+	var keep func(values []interface{}) bool
+	switch {
+	case len(args) == 1 && args[0] == "exists":
+		keep = func(values []interface{}) bool { return len(values) > 0 }
+	case len(args) == 1 && args[0] == "missing":
+		keep = func(values []interface{}) bool { return len(values) == 0 }
+	case len(args) == 2 && (args[0] == "==" || args[0] == "!="):
+		want := jsonEncoding(parseLiteral(args[1]))
+		equal := args[0] == "=="
+		keep = func(values []interface{}) bool {
+			for _, value := range values {
+				if jsonEncoding(value) == want {
+					return equal
+				}
+			}
+			return !equal
+		}
+	default:
+		return nil, errWhereCondition
+	}
+	return func(doc interface{}) ([]interface{}, error) {
+		if keep(valuesAt(doc, segments)) {
+			return []interface{}{doc}, nil
+		}
+		return nil, nil
+	}, nil
+}
+
+// apply runs the steps on every document, the elements of an array each
+// being a document, and returns the documents that result.
+func (t *docTransform) apply(docs []interface{}) ([]interface{}, error) {
+	if t == nil {
+		return docs, nil
+	}
+	var out []interface{}
+	for _, doc := range docs {
+		items, ok := doc.([]interface{})
+		if !ok {
+			items = []interface{}{doc}
+		}
+		for _, step := range t.steps {
+			var next []interface{}
+			for _, item := range items {
+				result, err := step(item)
+				if err != nil {
+					return nil, fmt.Errorf("--transform: %v", err)
+				}
+				next = append(next, result...)
+			}
+			items = next
+		}
+		out = append(out, items...)
+	}
+	return out, nil
+}
+
+// reorder moves the key positions recorded in order to where the steps
+// move the keys, as unwrap and rename do.
+func (t *docTransform) reorder(order *keyOrder) {
+	if t == nil {
+		return
+	}
+	for _, move := range t.moves {
+		if move != nil {
+			order.remap(move)
+		}
+	}
+}
+
+// matchKeyPath reports whether keys begin with the keys of segments, and
+// how many keys that is. Element segments match nothing, as arrays share the
+// key path of their elements.
+func matchKeyPath(segments []pathSegment, keys []string) (int, bool) {
+	n := 0
+	for _, s := range segments {
+		if s.element {
+			continue
+		}
+		if n == len(keys) || !s.matches(keys[n]) {
+			return 0, false
+		}
+		n++
+	}
+	return n, true
+}
+
+// valuesAt returns the values of doc at the path of segments.
+func valuesAt(doc interface{}, segments []pathSegment) []interface{} {
+	values := []interface{}{doc}
+	for _, s := range segments {
+		var next []interface{}
+		for _, value := range values {
+			switch value := value.(type) {
+			case map[string]interface{}:
+				if s.element {
+					continue
+				}
+				for _, key := range matchingKeys(value, s) {
+					next = append(next, value[key])
+				}
+			case []interface{}:
+				switch {
+				case !s.element:
+				case s.index < 0:
+					next = append(next, value...)
+				case s.index < len(value):
+					next = append(next, value[s.index])
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// matchingKeys returns the keys of object that the key segment s matches:
+// itself when quoted, otherwise as a glob.
+func matchingKeys(object map[string]interface{}, s pathSegment) []string {
+	if !s.glob() {
+		if _, ok := object[s.key]; ok {
+			return []string{s.key}
+		}
+		return nil
+	}
+	var keys []string
+	for key := range object {
+		if s.matches(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// glob reports whether the key segment s holds wildcards.
+func (s pathSegment) glob() bool {
+	return !s.quoted && strings.ContainsAny(s.key, "*?")
+}
+
+// matches reports whether the key segment s matches key.
+func (s pathSegment) matches(key string) bool {
+	if !s.glob() {
+		return key == s.key
+	}
+	ok, _ := path.Match(s.key, key)
+	return ok
+}
+
+// parseKey parses a key written as in a path: bare, or quoted in brackets.
+func parseKey(s string) (string, error) {
+	segments, err := parsePath(s)
+	if err != nil {
+		return "", err
+	}
+	if len(segments) != 1 || segments[0].element {
+		return "", fmt.Errorf("%s is not a single key", s)
+	}
+	return segments[0].key, nil
+}
+
+// parseLiteral parses a value to compare with: JSON, such as "on hold", 42
+// or null, or else a bare word standing for a string.
+func parseLiteral(s string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return s
+	}
+	return value
+}
+
+// splitOutsideQuotes splits s at the separator characters in seps that are
+// outside double quotes, dropping empty parts.
+func splitOutsideQuotes(s, seps string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.IndexByte(seps, s[i]) >= 0:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocTransform(t *testing.T) {
+	input := `[
+		{"type": "order", "data": {"id": 1, "mail": "a", "debug_x": 1, "items": [{"sku": "s", "sku_raw": 1}]}},
+		{"type": "ping", "data": {}},
+		{"type": "order", "data": [{"id": 2}, {"id": 3, "deleted": true}]}
+	]`
+
+	tests := []struct {
+		name      string
+		transform string
+		want      string // JSON of the resulting documents
+	}{
+		{"none", "", input},
+		{"where equal", `where type == "ping"`, `[{"type": "ping", "data": {}}]`},
+		{"where bare word", "where type != order", `[{"type": "ping", "data": {}}]`},
+		{"where missing", "where data.id missing; where data[].id missing", `[{"type": "ping", "data": {}}]`},
+		{"unwrap", "where type == order; unwrap data; where deleted missing; drop debug_*, drop items[].*_raw; rename mail email",
+			`[{"id": 1, "email": "a", "items": [{"sku": "s"}]}, {"id": 2}]`},
+		{"quoted keys", `rename type ["kind; of"]; where ["kind; of"] == "ping"`, `[{"kind; of": "ping", "data": {}}]`},
+		{"rename onto itself", `where type == ping; rename typ? type`, `[{"type": "ping", "data": {}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs, want []interface{}
+			if err := json.Unmarshal([]byte(input), &docs); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			transform, err := parseTransform(tt.transform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := transform.apply([]interface{}{docs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.transform == "" {
+				got = got[0].([]interface{})
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("apply() = %s, want %s", jsonEncoding(got), jsonEncoding(want))
+			}
+		})
+	}
+}
+
+func TestDocTransformRenameErrors(t *testing.T) {
+	tests := []struct {
+		transform string
+		want      string
+	}{
+		{"rename mail email", "--transform: rename mail email: the object already holds email"},
+		{"rename *_at ts", "--transform: rename *_at ts: several keys match (created_at, updated_at)"},
+	}
+	for _, tt := range tests {
+		doc := map[string]interface{}{"mail": "a", "email": "b", "created_at": 1.0, "updated_at": 2.0}
+		transform, err := parseTransform(tt.transform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := transform.apply([]interface{}{doc}); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: error %v, want %q", tt.transform, err, tt.want)
+		}
+		if doc["mail"] != "a" || doc["email"] != "b" {
+			t.Errorf("%s: document changed to %v", tt.transform, doc)
+		}
+	}
+}
+
+func TestParseTransformErrors(t *testing.T) {
+	tests := []struct {
+		transform string
+		want      string
+	}{
+		{"frob x", `unknown step "frob"`},
+		{"unwrap", "unwrap takes 1 arguments, got 0"},
+		{"where type", "where takes a path and a condition"},
+		{"where type > 1", "where takes a path and a condition"},
+		{"drop items[]", "items[] does not end with a key"},
+		{"rename a b.c", "b.c is not a single key"},
+		{"drop a..b", "invalid path"},
+	}
+	for _, tt := range tests {
+		_, err := parseTransform(tt.transform)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseTransform(%q) error = %v, want %q", tt.transform, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// violation is a way a document does not match the schema, at the path of
+// the offending value: "items[2].id". Its kind is one of violationKinds.
+type violation struct {
+	path    string
+	kind    string
+	message string
+}
+
+// violationKinds classify violations: missing required fields, values of the
+// wrong type, values outside an enum, and values breaking a minimum,
+// maximum, length or item count constraint.
+var violationKinds = []string{"missing", "type", "enum", "range"}
+
+func (v violation) String() string {
+	path := v.path
+	if path == "" {
+		path = "(root)"
+	}
+	return path + "\t" + v.message
+}
+
+// schemaValidator checks documents against a JSON Schema, as written by the
+// jsonschema format: type, properties, required, items, enum and the
+// minimum, maximum, minLength, maxLength, minItems and maxItems
+// constraints. Other keywords are ignored. It records which parts of the
+// schema the documents exercised, for coverage.
+type schemaValidator struct {
+	root *jsonSchema
+	uses map[*jsonSchema]*schemaUse
+}
+
+// schemaUse is what the documents exercised of one schema: the JSON types of
+// the values that reached it and, for an enum, the members seen.
+type schemaUse struct {
+	types  map[string]bool
+	values map[string]bool // JSON encodings
+}
+
+func newSchemaValidator(root *jsonSchema) *schemaValidator {
+	return &schemaValidator{root: root, uses: make(map[*jsonSchema]*schemaUse)}
+}
+
+// loadSchema reads a JSON Schema file.
+func loadSchema(name string) (*jsonSchema, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("%s: not a JSON Schema: %v", name, err)
+	}
+	return &schema, nil
+}
+
+// validate checks doc against the root schema.
+func (v *schemaValidator) validate(doc interface{}) []violation {
+	var violations []violation
+	v.check(v.root, doc, "", &violations)
+	return violations
+}
+
+func (v *schemaValidator) check(s *jsonSchema, value interface{}, path string, out *[]violation) {
+	if v.checkValue(s, value, path, out) {
+		v.checkChildren(s, value, path, out)
+	}
+}
+
+// checkValue checks the type, enum and, for numbers and strings, range
+// constraints of a value, but not its elements or members. It reports
+// whether the type matched, as the contents of a value of the wrong type
+// are not checked.
+func (v *schemaValidator) checkValue(s *jsonSchema, value interface{}, path string, out *[]violation) bool {
+	use := v.uses[s]
+	if use == nil {
+		use = &schemaUse{types: make(map[string]bool), values: make(map[string]bool)}
+		v.uses[s] = use
+	}
+	typ := jsonType(value)
+	use.types[typ] = true
+
+	if types := schemaTypes(s); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return typeMatches(t, value) }) {
+		*out = append(*out, violation{path, "type", fmt.Sprintf("want %s, got %s", strings.Join(types, " or "), typ)})
+		return false
+	}
+	if len(s.Enum) > 0 {
+		// Only members are recorded, so that values outside the enum, which
+		// may be unbounded, do not pile up.
+		encoded := jsonEncoding(value)
+		if slices.ContainsFunc(s.Enum, func(e interface{}) bool { return jsonEncoding(e) == encoded }) {
+			use.values[encoded] = true
+		} else {
+			*out = append(*out, violation{path, "enum", fmt.Sprintf("%s is not one of the enum values", encoded)})
+		}
+	}
+
+	switch value := value.(type) {
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			*out = append(*out, violation{path, "range", fmt.Sprintf("%s is less than the minimum %s", formatNumber(value), formatNumber(*s.Minimum))})
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			*out = append(*out, violation{path, "range", fmt.Sprintf("%s is more than the maximum %s", formatNumber(value), formatNumber(*s.Maximum))})
+		}
+	case string:
+		n := utf8.RuneCountInString(value)
+		if s.MinLength != nil && n < *s.MinLength {
+			*out = append(*out, violation{path, "range", fmt.Sprintf("length %d is less than minLength %d", n, *s.MinLength)})
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			*out = append(*out, violation{path, "range", fmt.Sprintf("length %d is more than maxLength %d", n, *s.MaxLength)})
+		}
+	}
+	return true
+}
+
+// checkChildren checks the elements of an array and the members of an
+// object.
+func (v *schemaValidator) checkChildren(s *jsonSchema, value interface{}, path string, out *[]violation) {
+	switch value := value.(type) {
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			*out = append(*out, violation{path, "range", fmt.Sprintf("%d items, less than minItems %d", len(value), *s.MinItems)})
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			*out = append(*out, violation{path, "range", fmt.Sprintf("%d items, more than maxItems %d", len(value), *s.MaxItems)})
+		}
+		if s.Items != nil {
+			for i, item := range value {
+				v.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i), out)
+			}
+		}
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := value[key]; !ok {
+				*out = append(*out, violation{fieldPath(path, key), "missing", "missing"})
+			}
+		}
+		for _, key := range s.Properties.keys {
+			if member, ok := value[key]; ok {
+				v.check(s.Properties.schemas[key], member, fieldPath(path, key), out)
+			}
+		}
+	}
+}
+
+// schemaTypes returns the types a schema allows, or nil for any type.
+func schemaTypes(s *jsonSchema) []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, t := range t {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonType returns the JSON Schema type of a decoded value.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func typeMatches(typ string, value interface{}) bool {
+	if f, ok := value.(float64); ok && typ == "integer" {
+		return f == float64(int64(f))
+	}
+	return jsonType(value) == typ
+}
+
+func jsonEncoding(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// schemaCoverage is the share of a schema that validated documents
+// exercised, and the parts they did not, as tab-separated lines.
+type schemaCoverage struct {
+	fields, fieldsSeen int
+	values, valuesSeen int
+	unseen             []string
+}
+
+// coverage reports the fields no document held, the types a field allows
+// but never had and the enum values never seen. Only the outermost of
+// nested unseen fields is listed.
+func (v *schemaValidator) coverage() schemaCoverage {
+	var c schemaCoverage
+	// reached is whether a value reached s or, for the items of an array,
+	// the array, so that the fields under s are worth listing.
+	var walk func(s *jsonSchema, path string, reached bool)
+	walk = func(s *jsonSchema, path string, reached bool) {
+		use := v.uses[s]
+		if types := schemaTypes(s); use != nil && len(types) > 1 {
+			for _, t := range types {
+				if !use.types[t] && !(t == "integer" && use.types["number"]) {
+					c.unseen = append(c.unseen, "unseen type\t"+path+"\t"+t)
+				}
+			}
+		}
+		for _, e := range s.Enum {
+			c.values++
+			switch {
+			case use != nil && use.values[jsonEncoding(e)]:
+				c.valuesSeen++
+			case use != nil:
+				c.unseen = append(c.unseen, "unseen value\t"+path+"\t"+jsonEncoding(e))
+			}
+		}
+		if s.Items != nil {
+			walk(s.Items, path+"[]", use != nil)
+		}
+		for _, key := range s.Properties.keys {
+			child := s.Properties.schemas[key]
+			childPath := fieldPath(path, key)
+			c.fields++
+			if v.uses[child] != nil {
+				c.fieldsSeen++
+			} else if reached {
+				c.unseen = append(c.unseen, "unseen field\t"+childPath)
+			}
+			walk(child, childPath, v.uses[child] != nil)
+		}
+	}
+	walk(v.root, "", true)
+	return c
+}
+
+func (c schemaCoverage) write(w io.Writer) {
+	summary := fmt.Sprintf("coverage: %d of %d fields seen", c.fieldsSeen, c.fields)
+	if c.values > 0 {
+		summary += fmt.Sprintf(", %d of %d enum values seen", c.valuesSeen, c.values)
+	}
+	fmt.Fprintln(w, summary)
+	for _, line := range c.unseen {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// validateReport validates the documents of sources with v, writing a line
+// to out for each violation, located by document and, for streamed JSON, by
+// byte offset. It returns the number of documents and of invalid ones.
+func validateReport(out io.Writer, sources []source, input string, opts *decodeOptions, v *schemaValidator, log io.Writer) (docs, invalid int, err error) {
+	docs, err = validateSources(sources, input, opts, v, log, func(doc int, src string, violations []locatedViolation) {
+		if len(violations) > 0 {
+			invalid++
+		}
+		for _, violation := range violations {
+			location := fmt.Sprintf("document %d", doc)
+			switch {
+			case violation.offset < 0:
+			case len(sources) > 1:
+				location += fmt.Sprintf(" at %s, byte %d", src, violation.offset)
+			default:
+				location += fmt.Sprintf(" at byte %d", violation.offset)
+			}
+			fmt.Fprintf(out, "%s\t%s\n", location, violation.violation)
+		}
+	})
+	return docs, invalid, err
+}
+
+// runValidate implements "json-shape validate --schema file [flags] [input]".
+// It prints a line per violation, prefixed with the number of the document
+// and, for JSON input, the byte offset of the offending value, and exits
+// with status 3 when a document does not match. Documents are validated as
+// they are read, so that memory does not grow with the input. Given several
+// schemas, it prints their conformance matrix instead.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("json-shape validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: json-shape validate --schema file [--schema file ...] [flags] [input]")
+		fs.PrintDefaults()
+	}
+	var schemaPaths []string
+	fs.Func("schema", "JSON Schema each document must match, e.g. one written with --format=jsonschema; repeat to compare how well the data matches each", func(path string) error {
+		schemaPaths = append(schemaPaths, path)
+		return nil
+	})
+	coverage := fs.Bool("coverage", false, "after the violations, report the fields, types and enum values of the schema the documents never exercised")
+	inputs := addInputFlags(fs)
+	fs.Parse(args)
+
+	if len(schemaPaths) == 0 || fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("validate takes a --schema and an optional input")
+	}
+	schemas := make([]*jsonSchema, len(schemaPaths))
+	for i, path := range schemaPaths {
+		var err error
+		if schemas[i], err = loadSchema(path); err != nil {
+			return err
+		}
+	}
+	if len(schemas) > 1 {
+		data, err := inputs.read(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		docs, ok := data.([]interface{})
+		if !ok {
+			docs = []interface{}{data}
+		}
+		rows := conformanceMatrix(schemaPaths, schemas, docs)
+		if err := writeConformance(os.Stdout, rows, len(docs)); err != nil {
+			return err
+		}
+		if rows[0].valid < len(docs) {
+			os.Exit(exitAssertionFailed)
+		}
+		return nil
+	}
+
+	input, opts, err := inputs.options()
+	if err != nil {
+		return err
+	}
+	sources, err := resolveSources(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	v := newSchemaValidator(schemas[0])
+	docs, invalid, err := validateReport(os.Stdout, sources, input, opts, v, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if docs == 0 {
+		return fmt.Errorf("no documents found in input")
+	}
+	if *coverage {
+		v.coverage().write(os.Stdout)
+	}
+	fmt.Fprintf(os.Stderr, "%d documents, %d invalid\n", docs, invalid)
+	if invalid > 0 {
+		os.Exit(exitAssertionFailed)
+	}
+	return nil
+}
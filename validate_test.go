@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer", "minimum": 1},
+    "status": {"type": "string", "enum": ["open", "closed", "pending"]},
+    "note": {"type": ["string", "null"], "maxLength": 5},
+    "tags": {"type": "array", "maxItems": 2, "items": {"type": "object", "properties": {"k": {"type": "string"}}, "required": ["k"]}},
+    "legacy": {"type": "object", "properties": {"code": {"type": "number"}}}
+  },
+  "required": ["id", "status"]
+}`
+
+func parseTestSchema(t *testing.T) *jsonSchema {
+	t.Helper()
+	var schema jsonSchema
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func TestSchemaValidator(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"valid", `{"id": 1, "status": "open", "note": null, "tags": [{"k": "a"}]}`, nil},
+		{"missing", `{"note": "x"}`, []string{"id\tmissing", "status\tmissing"}},
+		{"wrong types", `{"id": 1.5, "status": 3}`, []string{"id\twant integer, got number", "status\twant string, got number"}},
+		{"enum", `{"id": 1, "status": "gone"}`, []string{`status` + "\t" + `"gone" is not one of the enum values`}},
+		{"constraints", `{"id": 0, "status": "open", "note": "toolong", "tags": [{"k": "a"}, {"k": "b"}, {}]}`, []string{
+			"id\t0 is less than the minimum 1",
+			"note\tlength 7 is more than maxLength 5",
+			"tags\t3 items, more than maxItems 2",
+			"tags[2].k\tmissing",
+		}},
+		{"not an object", `[1]`, []string{"(root)\twant object, got array"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range newSchemaValidator(parseTestSchema(t)).validate(doc) {
+				got = append(got, v.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaCoverage(t *testing.T) {
+	v := newSchemaValidator(parseTestSchema(t))
+	for _, doc := range []string{
+		`{"id": 1, "status": "open", "note": "a", "tags": []}`,
+		`{"id": 2, "status": "closed"}`,
+		`{"id": 3, "status": "archived"}`,
+	} {
+		var data interface{}
+		if err := json.Unmarshal([]byte(doc), &data); err != nil {
+			t.Fatal(err)
+		}
+		v.validate(data)
+	}
+
+	for s, use := range v.uses {
+		if len(s.Enum) > 0 && use.values[`"archived"`] {
+			t.Error("a value outside the enum was recorded")
+		}
+	}
+
+	var buf bytes.Buffer
+	v.coverage().write(&buf)
+	want := "coverage: 4 of 7 fields seen, 2 of 3 enum values seen\n" +
+		"unseen value\tstatus\t\"pending\"\n" +
+		"unseen type\tnote\tnull\n" +
+		"unseen field\ttags[].k\n" +
+		"unseen field\tlegacy\n"
+	if buf.String() != want {
+		t.Errorf("coverage:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSchemaPropertiesRoundTrip(t *testing.T) {
+	schema := parseTestSchema(t)
+	if want := []string{"id", "status", "note", "tags", "legacy"}; !reflect.DeepEqual(schema.Properties.keys, want) {
+		t.Errorf("property order = %q, want %q", schema.Properties.keys, want)
+	}
+	out, err := json.Marshal(schema.Properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again schemaProperties
+	if err := json.Unmarshal(out, &again); err != nil || !reflect.DeepEqual(again.keys, schema.Properties.keys) {
+		t.Errorf("round trip: keys %q, error %v", again.keys, err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"unicode/utf8"
+	"unique"
+)
+
+// Bounds on the values kept per field, so that memory grows with the number
+// of fields rather than the number of records.
+const (
+	// maxExactValues is the number of distinct strings counted exactly;
+	// past it, only an estimate is kept.
+	maxExactValues = 256
+	// maxExactValueLen is the length of the longest string kept. A longer
+	// one switches the field to estimates, as such fields (descriptions,
+	// tokens, payloads) are not enums.
+	maxExactValueLen = 64
+	// hllPrecision sets the number of HyperLogLog registers, 2^11 bytes per
+	// field, for a standard error of about 2.3%.
+	hllPrecision = 11
+	// maxExampleBytes caps the example strings kept, which are only ever
+	// shown truncated.
+	maxExampleBytes = 256
+)
+
+var valueHashSeed = maphash.MakeSeed()
+
+// distinctValues counts the distinct strings of a field. The first
+// maxExactValues short strings are kept, interned so that values repeated
+// across fields are stored once, with how often each was seen. Past that,
+// the values are dropped and their number is estimated with a HyperLogLog
+// sketch.
+type distinctValues struct {
+	exact  map[unique.Handle[string]]int
+	sketch *hyperLogLog
+}
+
+func (d *distinctValues) add(v string, n int) {
+	if d.sketch == nil && len(v) <= maxExactValueLen {
+		h := unique.Make(v)
+		if _, seen := d.exact[h]; seen || len(d.exact) < maxExactValues {
+			if d.exact == nil {
+				d.exact = make(map[unique.Handle[string]]int)
+			}
+			d.exact[h] += n
+			return
+		}
+	}
+	d.toSketch()
+	d.sketch.add(v)
+}
+
+// toSketch switches d from exact counts to an estimate.
+func (d *distinctValues) toSketch() {
+	if d.sketch != nil {
+		return
+	}
+	d.sketch = &hyperLogLog{}
+	for h := range d.exact {
+		d.sketch.add(h.Value())
+	}
+	d.exact = nil
+}
+
+func (d *distinctValues) merge(o *distinctValues) {
+	if o.sketch != nil {
+		d.toSketch()
+		d.sketch.merge(o.sketch)
+		return
+	}
+	for h, n := range o.exact {
+		d.add(h.Value(), n)
+	}
+}
+
+// count returns the number of distinct values and whether it is exact.
+func (d *distinctValues) count() (int, bool) {
+	if d.sketch != nil {
+		return d.sketch.estimate(), false
+	}
+	return len(d.exact), true
+}
+
+// counts returns how often each value was seen, or nil once the values are
+// only estimated.
+func (d *distinctValues) counts() map[string]int {
+	if d.sketch != nil {
+		return nil
+	}
+	counts := make(map[string]int, len(d.exact))
+	for h, n := range d.exact {
+		counts[h.Value()] = n
+	}
+	return counts
+}
+
+// hyperLogLog estimates the number of distinct strings added to it
+// (Flajolet et al., with linear counting for small cardinalities).
+type hyperLogLog struct {
+	registers [1 << hllPrecision]uint8
+}
+
+func (h *hyperLogLog) add(v string) {
+	x := maphash.String(valueHashSeed, v)
+	i := x >> (64 - hllPrecision)
+	// The low bit set past the index bounds the run of leading zeros.
+	w := x<<hllPrecision | 1<<(hllPrecision-1)
+	h.registers[i] = max(h.registers[i], uint8(bits.LeadingZeros64(w))+1)
+}
+
+func (h *hyperLogLog) merge(o *hyperLogLog) {
+	for i, r := range o.registers {
+		h.registers[i] = max(h.registers[i], r)
+	}
+}
+
+func (h *hyperLogLog) estimate() int {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int(math.Round(e))
+}
+
+// truncateExample shortens a long example string to at most
+// maxExampleBytes, on a character boundary.
+func truncateExample(s string) string {
+	if len(s) <= maxExampleBytes {
+		return s
+	}
+	end := maxExampleBytes
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDistinctValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []string
+		wantCount int
+		wantExact bool
+	}{
+		{"empty", nil, 0, true},
+		{"repeated", []string{"a", "b", "a", "a"}, 2, true},
+		{"at cap", numbered(maxExactValues), maxExactValues, true},
+		{"past cap", numbered(maxExactValues + 1), maxExactValues + 1, false},
+		{"long value", []string{"a", strings.Repeat("x", maxExactValueLen+1)}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d distinctValues
+			for _, v := range tt.values {
+				d.add(v, 1)
+			}
+			count, exact := d.count()
+			// Estimates are within 10% of the count.
+			if exact != tt.wantExact || math.Abs(float64(count-tt.wantCount)) > float64(tt.wantCount)*0.1 {
+				t.Errorf("count() = %d, %v, want %d, %v", count, exact, tt.wantCount, tt.wantExact)
+			}
+			if counts := d.counts(); (counts == nil) == exact {
+				t.Errorf("counts() = %v with exact %v", counts, exact)
+			}
+		})
+	}
+}
+
+func TestDistinctValuesMerge(t *testing.T) {
+	var a, b, c distinctValues
+	a.add("x", 2)
+	b.add("x", 1)
+	b.add("y", 1)
+	a.merge(&b)
+	if counts := a.counts(); counts["x"] != 3 || counts["y"] != 1 || len(counts) != 2 {
+		t.Errorf("merged exact counts = %v", counts)
+	}
+
+	for _, v := range numbered(1000) {
+		c.add(v, 1)
+	}
+	a.merge(&c)
+	if count, exact := a.count(); exact || math.Abs(float64(count-1002)) > 1002*0.1 {
+		t.Errorf("merged estimate = %d, %v, want about 1002", count, exact)
+	}
+}
+
+func TestHyperLogLogEstimate(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		var h hyperLogLog
+		for _, v := range numbered(n) {
+			h.add(v)
+			h.add(v)
+		}
+		if got := h.estimate(); math.Abs(float64(got-n)) > float64(n)*0.1 {
+			t.Errorf("estimate() of %d values = %d", n, got)
+		}
+	}
+}
+
+func TestTruncateExample(t *testing.T) {
+	long := strings.Repeat("é", maxExampleBytes)
+	tests := []struct {
+		in      string
+		wantLen int
+	}{
+		{"short", 5},
+		{strings.Repeat("x", maxExampleBytes+1), maxExampleBytes},
+		{long, maxExampleBytes},
+		{"x" + long, maxExampleBytes - 1},
+	}
+	for _, tt := range tests {
+		got := truncateExample(tt.in)
+		if len(got) != tt.wantLen || !strings.HasPrefix(tt.in, got) {
+			t.Errorf("truncateExample(%.10q...) has length %d, want %d", tt.in, len(got), tt.wantLen)
+		}
+	}
+}
+
+func numbered(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	return values
+}
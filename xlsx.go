@@ -0,0 +1,272 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.T
+	for _, r := range t.Runs {
+		s += r.T
+	}
+	return s
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// xlsxBook holds the workbook-wide data needed to interpret cells.
+type xlsxBook struct {
+	strings   []string
+	dateStyle []bool // by cell style index
+	date1904  bool
+}
+
+// readXLSX returns the cell values of one worksheet of an Office Open XML
+// workbook. Cells formatted as dates become date strings.
+func readXLSX(zr *zip.Reader, sheet string, size *sheetSize) ([][]interface{}, error) {
+	var workbook struct {
+		Props struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(zr, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(workbook.Sheets))
+	for i, s := range workbook.Sheets {
+		names[i] = s.Name
+	}
+	index, err := selectSheet(names, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	target := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[index].ID {
+			target = rel.Target
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("sheet %q has no worksheet part", names[index])
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	book := &xlsxBook{date1904: workbook.Props.Date1904}
+	if err := book.readSharedStrings(zr); err != nil {
+		return nil, err
+	}
+	if err := book.readStyles(zr); err != nil {
+		return nil, err
+	}
+	return book.readRows(zr, target, size)
+}
+
+func (b *xlsxBook) readSharedStrings(zr *zip.Reader) error {
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := decodeZipXML(zr, "xl/sharedStrings.xml", &sst); err != nil && !errors.Is(err, errZipMissing) {
+		return err
+	}
+	for _, item := range sst.Items {
+		b.strings = append(b.strings, item.String())
+	}
+	return nil
+}
+
+// readStyles records which cell styles use a date or time number format.
+func (b *xlsxBook) readStyles(zr *zip.Reader) error {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeZipXML(zr, "xl/styles.xml", &styles); err != nil && !errors.Is(err, errZipMissing) {
+		return err
+	}
+
+	customDate := make(map[int]bool)
+	for _, f := range styles.NumFmts {
+		customDate[f.ID] = isDateFormat(f.Code)
+	}
+	for _, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		builtinDate := (id >= 14 && id <= 22) || (id >= 45 && id <= 47)
+		b.dateStyle = append(b.dateStyle, builtinDate || customDate[id])
+	}
+	return nil
+}
+
+// isDateFormat reports whether a custom number format displays a date or
+// time, ignoring quoted literals and bracketed colors.
+func isDateFormat(code string) bool {
+	inQuote, inBracket := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case strings.ContainsRune("ymdhs", r):
+			return true
+		}
+	}
+	return false
+}
+
+func (b *xlsxBook) readRows(zr *zip.Reader, name string, size *sheetSize) ([][]interface{}, error) {
+	f, err := openZipFile(zr, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows [][]interface{}
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row struct {
+			Cells []xlsxCell `xml:"c"`
+		}
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		var values []interface{}
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				if col, err = columnIndex(cell.Ref); err != nil {
+					return nil, fmt.Errorf("%s: %v", name, err)
+				}
+			}
+			if values, err = setCell(values, col, b.cellValue(cell)); err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if err := size.add(values, 1); err != nil {
+			return nil, err
+		}
+		rows = append(rows, values)
+	}
+}
+
+func (b *xlsxBook) cellValue(c xlsxCell) interface{} {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(b.strings) {
+			return nil
+		}
+		return b.strings[i]
+	case "inlineStr":
+		return c.Inline.String()
+	case "str", "e", "d":
+		return c.Value
+	case "b":
+		return c.Value == "1"
+	}
+
+	if c.Value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return c.Value
+	}
+	if c.Style >= 0 && c.Style < len(b.dateStyle) && b.dateStyle[c.Style] {
+		return b.serialDate(f)
+	}
+	return f
+}
+
+// serialDate converts a spreadsheet serial day number to an ISO 8601 date,
+// or date and time when it has a fractional part.
+func (b *xlsxBook) serialDate(serial float64) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if b.date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days, frac := math.Modf(serial)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round(frac*86400)) * time.Second)
+	if frac == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "AB12".
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		if col = col*26 + int(r-'A'+1); col > maxColumns {
+			return 0, fmt.Errorf("cell %s: column beyond the limit of %d columns", ref, maxColumns)
+		}
+	}
+	if col == 0 {
+		return 0, fmt.Errorf("cell reference %q has no column", ref)
+	}
+	return col - 1, nil
+}
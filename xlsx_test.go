@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func xlsxWorkbook(t *testing.T) []byte {
+	return zipArchive(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Orders" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>id</t></si><si><t>placed</t></si><si><r><t>cust</t></r><r><t>omer</t></r></si><si><t>Ada</t></si></sst>`,
+		"xl/styles.xml": `<styleSheet><numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd hh:mm"/><numFmt numFmtId="165" formatCode="[Red]0.00"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>note</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="str"><v>paid</v></c><c r="E1" t="str"><v>total</v></c></row>
+<row r="2"><c r="A2"><v>1</v></c><c r="B2" s="1"><v>45292</v></c><c r="C2" t="s"><v>3</v></c><c r="D2" t="b"><v>1</v></c><c r="E2" s="3"><v>9.5</v></c></row>
+<row r="4"><c r="A4"><v>2</v></c><c r="B4" s="2"><v>45292.5</v></c></row>
+</sheetData></worksheet>`,
+	})
+}
+
+func TestXLSXDecoder(t *testing.T) {
+	data := xlsxWorkbook(t)
+	result, err := newXLSXDecoder(bytes.NewReader(data), &decodeOptions{sheet: "Orders"}).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"id": 1.0, "placed": "2024-01-01", "customer": "Ada", "paid": true, "total": 9.5},
+		map[string]interface{}{"id": 2.0, "placed": "2024-01-01T12:00:00"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Decode() = %#v; want %#v", result, expected)
+	}
+
+	fields := analyzeJSON(result)
+	if !fields["customer"].Optional || fields["id"].Optional {
+		t.Errorf("expected only cells missing from some rows to be optional")
+	}
+}
+
+func TestXLSXDecoderSheetSelection(t *testing.T) {
+	data := xlsxWorkbook(t)
+	result, err := newXLSXDecoder(bytes.NewReader(data), nil).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if rows := result.([]interface{}); len(rows) != 0 {
+		t.Errorf("expected the header-only first sheet to have no rows, got %v", rows)
+	}
+
+	if _, err := newXLSXDecoder(bytes.NewReader(data), &decodeOptions{sheet: "Missing"}).Decode(); err == nil {
+		t.Error("expected an error for an unknown sheet")
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	tests := map[string]bool{
+		"yyyy-mm-dd":      true,
+		"h:mm AM/PM":      true,
+		"0.00":            false,
+		"[Red]#,##0":      false,
+		`"days "0`:        false,
+		"[$-409]d-mmm-yy": true,
+	}
+	for code, expected := range tests {
+		if result := isDateFormat(code); result != expected {
+			t.Errorf("isDateFormat(%q) = %v; want %v", code, result, expected)
+		}
+	}
+}
+
+func TestXLSXDecoderBadCellReference(t *testing.T) {
+	tests := []string{"12", "a1", "XFE1", "ZZZZZZZZZZZZZZZ1"}
+	for _, ref := range tests {
+		data := zipArchive(t, map[string]string{
+			"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml":   `<worksheet><sheetData><row r="1"><c r="` + ref + `" t="str"><v>x</v></c></row></sheetData></worksheet>`,
+		})
+		_, err := newXLSXDecoder(bytes.NewReader(data), nil).Decode()
+		if err == nil || !strings.Contains(err.Error(), "column") {
+			t.Errorf("cell %s: got %v, expected a column error", ref, err)
+		}
+	}
+}
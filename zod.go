@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// validatorRootName names the exported schema and type in zod and io-ts
+// output.
+const validatorRootName = "Root"
+
+// jsIdentifier matches keys that can be written unquoted in an object literal.
+var jsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// jsKey returns key as written in a TypeScript object literal.
+func jsKey(key string) string {
+	if jsIdentifier.MatchString(key) {
+		return key
+	}
+	return jsString(key)
+}
+
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// writeZod writes a zod schema validating each object of the input. Fields
+// missing from some objects are .optional(), fields seen as null are
+// .nullable(), and strings with few distinct values become enums. Fields
+// whose optionality was assumed get a comment saying so.
+func writeZod(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	var b strings.Builder
+	b.WriteString("import { z } from \"zod\";\n\n")
+	fmt.Fprintf(&b, "export const %s = %s;\n\n", validatorRootName, zodObject(fields, ""))
+	fmt.Fprintf(&b, "export type %s = z.infer<typeof %s>;\n", validatorRootName, validatorRootName)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func zodObject(fields map[string]*FieldInfo, indent string) string {
+	if len(fields) == 0 {
+		return "z.object({})"
+	}
+	var b strings.Builder
+	b.WriteString("z.object({\n")
+	for _, key := range sortedKeys(fields) {
+		field := fields[key]
+		schema := zodField(field, indent+"  ")
+		if field.Nullable && field.Type != "unknown" {
+			schema += ".nullable()"
+		}
+		if field.Optional {
+			schema += ".optional()"
+		}
+		fmt.Fprintf(&b, "%s  %s: %s,%s\n", indent, jsKey(key), schema, assumedComment(field))
+	}
+	b.WriteString(indent + "})")
+	return b.String()
+}
+
+func zodField(field *FieldInfo, indent string) string {
+	switch {
+	case field.Type == "" && field.isArray:
+		return "z.array(" + zodObject(field.Children, indent) + ")"
+	case field.Type == "":
+		return zodObject(field.Children, indent)
+	case field.Type == "string":
+		if values := field.stats.enumValues(); values != nil {
+			quoted := make([]string, len(values))
+			for i, v := range values {
+				quoted[i] = jsString(v)
+			}
+			return "z.enum([" + strings.Join(quoted, ", ") + "])"
+		}
+	}
+	return zodType(field.Type)
+}
+
+// zodType returns the schema of a leaf type such as "string" or
+// "array<number>".
+func zodType(typ string) string {
+	if strings.HasPrefix(typ, "array<") && strings.HasSuffix(typ, ">") {
+		if elem := typ[len("array<") : len(typ)-1]; elem != "unknown" {
+			return "z.array(" + zodType(elem) + ")"
+		}
+		return "z.array(z.unknown())"
+	}
+	switch typ {
+	case "string", "number", "boolean":
+		return "z." + typ + "()"
+	case "unknown":
+		// Only ever seen as null.
+		return "z.null()"
+	}
+	return "z.unknown()"
+}
+
+// writeIOTS writes an io-ts codec validating each object of the input, with
+// the same optionality, nullability and enum rules as writeZod. Objects with
+// optional fields are the intersection of a t.type of the required fields and
+// a t.partial of the rest.
+func writeIOTS(w io.Writer, fields map[string]*FieldInfo, opts *renderOptions) error {
+	var b strings.Builder
+	b.WriteString("import * as t from \"io-ts\";\n\n")
+	fmt.Fprintf(&b, "export const %s = %s;\n\n", validatorRootName, iotsObject(fields, ""))
+	fmt.Fprintf(&b, "export type %s = t.TypeOf<typeof %s>;\n", validatorRootName, validatorRootName)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func iotsObject(fields map[string]*FieldInfo, indent string) string {
+	var required, optional []string
+	for _, key := range sortedKeys(fields) {
+		if fields[key].Optional {
+			optional = append(optional, key)
+		} else {
+			required = append(required, key)
+		}
+	}
+	if len(optional) == 0 {
+		return iotsProps("t.type", fields, required, indent)
+	}
+	if len(required) == 0 {
+		return iotsProps("t.partial", fields, optional, indent)
+	}
+	inner := indent + "  "
+	return "t.intersection([\n" +
+		inner + iotsProps("t.type", fields, required, inner) + ",\n" +
+		inner + iotsProps("t.partial", fields, optional, inner) + ",\n" +
+		indent + "])"
+}
+
+func iotsProps(combinator string, fields map[string]*FieldInfo, keys []string, indent string) string {
+	if len(keys) == 0 {
+		return combinator + "({})"
+	}
+	var b strings.Builder
+	b.WriteString(combinator + "({\n")
+	for _, key := range keys {
+		field := fields[key]
+		codec := iotsField(field, indent+"  ")
+		if field.Nullable && field.Type != "unknown" {
+			codec = "t.union([" + codec + ", t.null])"
+		}
+		fmt.Fprintf(&b, "%s  %s: %s,%s\n", indent, jsKey(key), codec, assumedComment(field))
+	}
+	b.WriteString(indent + "})")
+	return b.String()
+}
+
+func iotsField(field *FieldInfo, indent string) string {
+	switch {
+	case field.Type == "" && field.isArray:
+		return "t.array(" + iotsObject(field.Children, indent) + ")"
+	case field.Type == "":
+		return iotsObject(field.Children, indent)
+	case field.Type == "string":
+		if values := field.stats.enumValues(); values != nil {
+			literals := make([]string, len(values))
+			for i, v := range values {
+				literals[i] = "t.literal(" + jsString(v) + ")"
+			}
+			if len(literals) == 1 {
+				return literals[0]
+			}
+			return "t.union([" + strings.Join(literals, ", ") + "])"
+		}
+	}
+	return iotsType(field.Type)
+}
+
+// iotsType returns the codec of a leaf type such as "string" or
+// "array<number>".
+func iotsType(typ string) string {
+	if strings.HasPrefix(typ, "array<") && strings.HasSuffix(typ, ">") {
+		if elem := typ[len("array<") : len(typ)-1]; elem != "unknown" {
+			return "t.array(" + iotsType(elem) + ")"
+		}
+		return "t.array(t.unknown)"
+	}
+	switch typ {
+	case "string", "number", "boolean":
+		return "t." + typ
+	case "unknown":
+		// Only ever seen as null.
+		return "t.null"
+	}
+	return "t.unknown"
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func validatorTestData() []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"role":  "admin",
+			"name":  "Alice",
+			"bio":   nil,
+			"tags":  []interface{}{"a"},
+			"items": []interface{}{map[string]interface{}{"sku": "A"}, map[string]interface{}{"sku": "B", "qty": 2.0}},
+		},
+		map[string]interface{}{"role": "user", "name": "Bob", "bio": "hi", "tags": []interface{}{}},
+		map[string]interface{}{"role": "user", "name": "Cy", "bio": "yo", "first-name": "Cy"},
+		map[string]interface{}{"role": "admin", "name": "Di", "bio": "ok"},
+	}
+}
+
+func TestWriteZod(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeZod(&buf, analyzeJSON(validatorTestData()), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `import { z } from "zod";
+
+export const Root = z.object({
+  bio: z.string().nullable(),
+  "first-name": z.string().optional(),
+  items: z.array(z.object({
+    qty: z.number().optional(),
+    sku: z.string(),
+  })).optional(),
+  name: z.string(),
+  role: z.enum(["admin", "user"]),
+  tags: z.array(z.string()).optional(),
+});
+
+export type Root = z.infer<typeof Root>;
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestWriteIOTS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeIOTS(&buf, analyzeJSON(validatorTestData()), &renderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `import * as t from "io-ts";
+
+export const Root = t.intersection([
+  t.type({
+    bio: t.union([t.string, t.null]),
+    name: t.string,
+    role: t.union([t.literal("admin"), t.literal("user")]),
+  }),
+  t.partial({
+    "first-name": t.string,
+    items: t.array(t.intersection([
+      t.type({
+        sku: t.string,
+      }),
+      t.partial({
+        qty: t.number,
+      }),
+    ])),
+    tags: t.array(t.string),
+  }),
+]);
+
+export type Root = t.TypeOf<typeof Root>;
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestValidatorLeafTypes(t *testing.T) {
+	tests := []struct {
+		typ, zod, iots string
+	}{
+		{"string", "z.string()", "t.string"},
+		{"array<number>", "z.array(z.number())", "t.array(t.number)"},
+		{"array<array<boolean>>", "z.array(z.array(z.boolean()))", "t.array(t.array(t.boolean))"},
+		{"array<unknown>", "z.array(z.unknown())", "t.array(t.unknown)"},
+		{"unknown", "z.null()", "t.null"},
+	}
+	for _, tt := range tests {
+		if got := zodType(tt.typ); got != tt.zod {
+			t.Errorf("zodType(%q) = %q, want %q", tt.typ, got, tt.zod)
+		}
+		if got := iotsType(tt.typ); got != tt.iots {
+			t.Errorf("iotsType(%q) = %q, want %q", tt.typ, got, tt.iots)
+		}
+	}
+}